@@ -0,0 +1,121 @@
+package main
+
+import (
+	"go/ast"
+	"strings"
+)
+
+const (
+	aggregationArrow     = "o--"
+	multiplicityOne      = "1"
+	multiplicityOptional = "0..1"
+	multiplicityMany     = "*"
+)
+
+// typeReference is a named type a field refers to, with how many values of it the field holds
+type typeReference struct {
+	qualifier    string // the package name the type is qualified with, empty for types of the same package
+	name         string // the type name without package, e.g. User for *example.User
+	multiplicity string
+}
+
+// refersTo reports whether the reference, written in a type of the given namespace, may name the class
+func (r typeReference) refersTo(namespace, class string) bool {
+	if shortName(class) != r.name {
+		return false
+	}
+	local := class == namespace+"."+r.name
+	return local == (r.qualifier == "")
+}
+
+// fieldTypeReferences returns the named types of a field type with their multiplicity: one for T, optional for *T
+// and many for slices, arrays, maps and channels of T
+func fieldTypeReferences(expr ast.Expr, multiplicity string) []typeReference {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return []typeReference{{name: e.Name, multiplicity: multiplicity}}
+	case *ast.SelectorExpr:
+		qualifier := ""
+		if x, ok := e.X.(*ast.Ident); ok {
+			qualifier = x.Name
+		}
+		return []typeReference{{qualifier: qualifier, name: e.Sel.Name, multiplicity: multiplicity}}
+	case *ast.StarExpr:
+		if multiplicity == multiplicityOne {
+			multiplicity = multiplicityOptional
+		}
+		return fieldTypeReferences(e.X, multiplicity)
+	case *ast.ParenExpr:
+		return fieldTypeReferences(e.X, multiplicity)
+	case *ast.ArrayType:
+		return fieldTypeReferences(e.Elt, multiplicityMany)
+	case *ast.MapType:
+		return append(fieldTypeReferences(e.Key, multiplicityMany), fieldTypeReferences(e.Value, multiplicityMany)...)
+	case *ast.ChanType:
+		return fieldTypeReferences(e.Value, multiplicityMany)
+	case *ast.IndexExpr:
+		return fieldTypeReferences(e.X, multiplicity)
+	case *ast.IndexListExpr:
+		return fieldTypeReferences(e.X, multiplicity)
+	}
+	return nil
+}
+
+// shortName returns the last element of a dotted class name
+func shortName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// applyMultiplicities replaces the aggregations goplantuml draws per type with one aggregation per field, labelled
+// with the field name and the UML multiplicities of the field, e.g. "1" o-- "*" for a slice
+func applyMultiplicities(d *Diagram, idx *sourceIndex, aggregatePrivate bool) {
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		st, ok := t.spec.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		aggregations := []*Edge{}
+		for _, e := range d.Edges {
+			if e.Arrow == aggregationArrow && e.From == c.FullName() {
+				aggregations = append(aggregations, e)
+			}
+		}
+		if len(aggregations) == 0 {
+			continue
+		}
+
+		replaced := map[*Edge]bool{}
+		fields := []*Edge{}
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				if !ast.IsExported(name.Name) && !aggregatePrivate {
+					continue
+				}
+				for _, ref := range fieldTypeReferences(field.Type, multiplicityOne) {
+					for _, e := range aggregations {
+						if !ref.refersTo(c.Namespace, e.To) {
+							continue
+						}
+						replaced[e] = true
+						fields = append(fields, &Edge{
+							From:      e.From,
+							FromLabel: multiplicityOne,
+							Arrow:     aggregationArrow,
+							ToLabel:   ref.multiplicity,
+							To:        e.To,
+							Label:     name.Name,
+						})
+					}
+				}
+			}
+		}
+		d.RemoveEdges(func(e *Edge) bool { return replaced[e] })
+		for _, e := range fields {
+			d.AddEdge(e)
+		}
+	}
+}
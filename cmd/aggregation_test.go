@@ -0,0 +1,148 @@
+package main
+
+import (
+	"go/parser"
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestFieldTypeReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected []typeReference
+	}{
+		{
+			name:     "value",
+			expr:     "User",
+			expected: []typeReference{{name: "User", multiplicity: "1"}},
+		},
+		{
+			name:     "pointer",
+			expr:     "*example.User",
+			expected: []typeReference{{qualifier: "example", name: "User", multiplicity: "0..1"}},
+		},
+		{
+			name:     "slice of pointers",
+			expr:     "[]*User",
+			expected: []typeReference{{name: "User", multiplicity: "*"}},
+		},
+		{
+			name:     "pointer to slice",
+			expr:     "*[]User",
+			expected: []typeReference{{name: "User", multiplicity: "*"}},
+		},
+		{
+			name: "map",
+			expr: "map[Key]*User",
+			expected: []typeReference{
+				{name: "Key", multiplicity: "*"},
+				{name: "User", multiplicity: "*"},
+			},
+		},
+		{
+			name:     "generic type",
+			expr:     "List[User]",
+			expected: []typeReference{{name: "List", multiplicity: "1"}},
+		},
+		{
+			name:     "function",
+			expr:     "func(User) error",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := parser.ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpr() error = %v", err)
+			}
+			result := fieldTypeReferences(expr, multiplicityOne)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("fieldTypeReferences() = %v, want %v", result, tt.expected)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("fieldTypeReferences()[%d] = %+v, want %+v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyMultiplicities(t *testing.T) {
+	root := writeFiles(t, map[string]string{"store/store.go": `package store
+
+type User struct {
+	Friends []User
+	Manager *User
+	Groups  map[string]*Group
+	Primary Group
+	owner   *Group
+}
+
+type Group struct {
+	Members []*User
+}
+`})
+	dirs := []string{filepath.Join(root, "store")}
+
+	tests := []struct {
+		name             string
+		aggregatePrivate bool
+		expected         []string
+	}{
+		{
+			name: "public fields",
+			expected: []string{
+				`"store.Group" "1" o-- "*" "store.User" : Members`,
+				`"store.User" "1" o-- "*" "store.User" : Friends`,
+				`"store.User" "1" o-- "0..1" "store.User" : Manager`,
+				`"store.User" "1" o-- "*" "store.Group" : Groups`,
+				`"store.User" "1" o-- "1" "store.Group" : Primary`,
+			},
+		},
+		{
+			name:             "private fields",
+			aggregatePrivate: true,
+			expected: []string{
+				`"store.Group" "1" o-- "*" "store.User" : Members`,
+				`"store.User" "1" o-- "*" "store.User" : Friends`,
+				`"store.User" "1" o-- "0..1" "store.User" : Manager`,
+				`"store.User" "1" o-- "*" "store.Group" : Groups`,
+				`"store.User" "1" o-- "1" "store.Group" : Primary`,
+				`"store.User" "1" o-- "0..1" "store.Group" : owner`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+			if err != nil {
+				t.Fatalf("collectSources() error = %v", err)
+			}
+			result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", root, err)
+			}
+			_ = result.SetRenderingOptions(map[goplantuml.RenderingOption]any{
+				goplantuml.RenderAggregations:      true,
+				goplantuml.AggregatePrivateMembers: tt.aggregatePrivate,
+			})
+			d := ParseDiagram(result.Render())
+			applyMultiplicities(d, loadSourceIndex(sources, dirs), tt.aggregatePrivate)
+
+			edges := []string{}
+			for _, e := range d.Edges {
+				edges = append(edges, e.String())
+			}
+			if !equalStrings(edges, tt.expected) {
+				t.Errorf("Edges = %q, want %q", edges, tt.expected)
+			}
+		})
+	}
+}
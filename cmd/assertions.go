@@ -0,0 +1,118 @@
+package main
+
+import (
+	"go/ast"
+	"path"
+	"regexp"
+	"strconv"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// assertionArrow draws implementations asserted in the sources as a dashed realization, unlike the solid arrow of
+// implementations goplantuml and -typecheck infer
+const assertionArrow = "<|.."
+
+// assertionPattern finds files that may declare interface compliance assertions, so that sources without them are
+// not parsed twice
+var assertionPattern = regexp.MustCompile(`(?m)^\s*(var\s+)?_\s+[\w.*\[\], ]+=`)
+
+// assertedType returns the type expression of the value of an assertion: T for (*T)(nil), T{}, &T{} and new(T),
+// without type arguments
+func assertedType(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return assertedType(e.X)
+	case *ast.UnaryExpr:
+		return assertedType(e.X)
+	case *ast.StarExpr:
+		return assertedType(e.X)
+	case *ast.CompositeLit:
+		return assertedType(e.Type)
+	case *ast.CallExpr:
+		if ident, ok := e.Fun.(*ast.Ident); ok && ident.Name == "new" && len(e.Args) == 1 {
+			return assertedType(e.Args[0])
+		}
+		return assertedType(e.Fun)
+	case *ast.IndexExpr:
+		return e.X
+	case *ast.IndexListExpr:
+		return e.X
+	case *ast.Ident, *ast.SelectorExpr:
+		return e
+	}
+	return nil
+}
+
+// assertedInterface returns the class of the interface of an assertion. Interfaces that are not part of the
+// diagram are added as stubs marked as external, the same way -external-interfaces draws them.
+func assertedInterface(d *Diagram, r *classResolver, a *sourceAssertion) *Class {
+	if c := r.resolve(a.namespace, a.file, a.iface); c != nil {
+		if c.Kind != "interface" {
+			return nil
+		}
+		return c
+	}
+	stub := &Class{Kind: "interface", Stereotypes: []string{externalStereotype}}
+	switch e := a.iface.(type) {
+	case *ast.Ident:
+		if e.Name != "error" {
+			return nil
+		}
+		stub.Name = e.Name
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		for _, spec := range a.file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err == nil && importName(spec, importPath) == x.Name {
+				stub.Namespace = path.Base(importPath)
+			}
+		}
+		if stub.Namespace == "" {
+			return nil
+		}
+		stub.Name = e.Sel.Name
+	default:
+		return nil
+	}
+	if existing := d.FindClass(stub.FullName()); existing != nil {
+		return existing
+	}
+	d.AddClass(stub, false)
+	r.byName[stub.FullName()] = stub
+	return stub
+}
+
+// addAssertions draws the interface compliance assertions of the sources, e.g. var _ io.Reader = (*File)(nil), as
+// dashed realizations. They replace the implementation inferred for the same pair of classes.
+func addAssertions(d *Diagram, idx *sourceIndex, ro map[goplantuml.RenderingOption]any) {
+	if !renderingOption(ro, goplantuml.RenderImplementations, true) {
+		return
+	}
+	label := ""
+	if renderingOption(ro, goplantuml.RenderConnectionLabels, false) {
+		label = implementsLabel
+	}
+	r := newClassResolver(d)
+	for _, a := range idx.assertions {
+		typeExpr := assertedType(a.value)
+		if typeExpr == nil {
+			continue
+		}
+		target := r.resolve(a.namespace, a.file, typeExpr)
+		if target == nil || target.Kind != "class" {
+			continue
+		}
+		iface := assertedInterface(d, r, a)
+		if iface == nil {
+			continue
+		}
+		d.RemoveEdges(func(e *Edge) bool {
+			return e.Arrow == implementsArrow && e.From == iface.Ref() && e.To == target.Ref()
+		})
+		d.AddEdge(&Edge{From: iface.Ref(), Arrow: assertionArrow, ToLabel: label, To: target.Ref()})
+	}
+}
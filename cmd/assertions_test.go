@@ -0,0 +1,145 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestAssertedType(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "(*File)(nil)", expected: "File"},
+		{value: "File{}", expected: "File"},
+		{value: "&File{}", expected: "File"},
+		{value: "new(File)", expected: "File"},
+		{value: "(*Cache[string])(nil)", expected: "Cache"},
+		{value: "Pair[int, string]{}", expected: "Pair"},
+		{value: "(*fs.File)(nil)", expected: "fs.File"},
+		{value: "Code(0)", expected: "Code"},
+		{value: "nil", expected: "nil"},
+		{value: `"text"`, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			expr, err := parser.ParseExpr(tt.value)
+			if err != nil {
+				t.Fatalf("ParseExpr() error = %v", err)
+			}
+			result := ""
+			switch e := assertedType(expr).(type) {
+			case *ast.Ident:
+				result = e.Name
+			case *ast.SelectorExpr:
+				result = e.X.(*ast.Ident).Name + "." + e.Sel.Name
+			}
+			if result != tt.expected {
+				t.Errorf("assertedType(%s) = %q, want %q", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+// assertingSource asserts implementations of an interface of the diagram, of an imported one and of error
+const assertingSource = `package store
+
+import (
+	"io"
+	"net/http"
+)
+
+type Getter interface {
+	Get() string
+}
+
+type File struct{}
+
+func (f *File) Get() string                       { return "" }
+func (f *File) Read(p []byte) (int, error)        { return 0, nil }
+func (f *File) Error() string                     { return "" }
+func (f *File) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+var (
+	_ Getter       = (*File)(nil)
+	_ io.Reader    = &File{}
+	_ error        = new(File)
+	_ http.Handler = (*File)(nil)
+	_              = File{}
+)
+`
+
+func TestAddAssertions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  map[goplantuml.RenderingOption]any
+		expected []string
+		stubs    []string
+	}{
+		{
+			name:    "assertions replace inferred implementations",
+			options: map[goplantuml.RenderingOption]any{},
+			expected: []string{
+				`"store.Getter" <|.. "store.File"`,
+				`"io.Reader" <|.. "store.File"`,
+				`"error" <|.. "store.File"`,
+				`"http.Handler" <|.. "store.File"`,
+			},
+			stubs: []string{"io.Reader", "error", "http.Handler"},
+		},
+		{
+			name:    "labels",
+			options: map[goplantuml.RenderingOption]any{goplantuml.RenderConnectionLabels: true},
+			expected: []string{
+				`"store.Getter" <|.. "implements" "store.File"`,
+				`"io.Reader" <|.. "implements" "store.File"`,
+				`"error" <|.. "implements" "store.File"`,
+				`"http.Handler" <|.. "implements" "store.File"`,
+			},
+		},
+		{
+			name:     "implementations hidden",
+			options:  map[goplantuml.RenderingOption]any{goplantuml.RenderImplementations: false},
+			expected: []string{`"store.Getter" <|-- "store.File"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeFiles(t, map[string]string{"store/store.go": assertingSource})
+			dirs := []string{filepath.Join(root, "store")}
+			sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+			if err != nil {
+				t.Fatalf("collectSources() error = %v", err)
+			}
+			if !sources.assertions {
+				t.Fatalf("collectSources() found no assertions")
+			}
+			result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", root, err)
+			}
+			d := ParseDiagram(result.Render())
+			addAssertions(d, loadSourceIndex(sources, dirs), tt.options)
+
+			edges := []string{}
+			for _, e := range d.Edges {
+				edges = append(edges, e.String())
+			}
+			if !equalStrings(edges, tt.expected) {
+				t.Errorf("Edges = %q, want %q", edges, tt.expected)
+			}
+			for _, name := range tt.stubs {
+				stub := d.FindClass(name)
+				if stub == nil || stub.Kind != "interface" || !containsString(stub.Stereotypes, externalStereotype) {
+					t.Errorf("FindClass(%s) = %v, want an external interface stub", name, stub)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"go/ast"
+	"go/token"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// changedSinceColor is the accent color of the classes -changed-since finds changes in
+const changedSinceColor = "#Gold"
+
+// hunkHeader matches the header of a hunk of a unified diff, the groups are the first line and the number of lines of
+// the new version
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// lineRange is an inclusive range of line numbers
+type lineRange struct {
+	start int
+	end   int
+}
+
+// fileChanges are the changed lines of files by absolute path
+type fileChanges map[string][]lineRange
+
+// lines returns the changed lines of a file, which may be given by a path through symbolic links
+func (c fileChanges) lines(path string) []lineRange {
+	if ranges, ok := c[path]; ok {
+		return ranges
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return c[resolved]
+	}
+	return nil
+}
+
+// touches reports whether a changed line lies within the lines of a node
+func (c fileChanges) touches(fset *token.FileSet, node ast.Node) bool {
+	start, end := fset.Position(node.Pos()), fset.Position(node.End())
+	for _, r := range c.lines(start.Filename) {
+		if r.start <= end.Line && r.end >= start.Line {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDiffHunks returns the changed lines of the new version of the files of a unified diff without context, by
+// their path in the diff. Lines that were only removed count as a change of the line before them.
+func parseDiffHunks(diff string) map[string][]lineRange {
+	result := map[string][]lineRange{}
+	file := ""
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "+++ "); ok {
+			// git ends names with spaces with a tab and quotes names with special characters
+			name = strings.TrimSuffix(name, "\t")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			file = ""
+			if name != "/dev/null" {
+				file = strings.TrimPrefix(name, "b/")
+			}
+			continue
+		}
+		match := hunkHeader.FindStringSubmatch(line)
+		if match == nil || file == "" {
+			continue
+		}
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+		end := start + count - 1
+		if count == 0 {
+			end = start
+		}
+		result[file] = append(result[file], lineRange{start: start, end: end})
+	}
+	return result
+}
+
+// changedLines asks git which lines of Go files in the working trees of the repositories the directories belong to
+// changed since a revision. Files git does not track yet are changed as a whole.
+func changedLines(dirs []string, revision string) (fileChanges, error) {
+	result := fileChanges{}
+	roots := map[string]bool{}
+	for _, dir := range dirs {
+		root, err := runGit(dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, err
+		}
+		root = strings.TrimSpace(root)
+		if roots[root] {
+			continue
+		}
+		roots[root] = true
+		if err := repositoryChanges(root, revision, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// repositoryChanges adds the changed lines of Go files in the working tree of the repository at root to the result
+func repositoryChanges(root, revision string, result fileChanges) error {
+	diff, err := runGit(
+		root,
+		"-c", "core.quotePath=false",
+		"diff", "--unified=0", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/",
+		revision, "--", "*.go",
+	)
+	if err != nil {
+		return err
+	}
+	untracked, err := runGit(root, "ls-files", "-z", "--others", "--exclude-standard", "--full-name", "--", "*.go")
+	if err != nil {
+		return err
+	}
+	hunks := parseDiffHunks(diff)
+	for _, file := range strings.Split(untracked, "\x00") {
+		if file != "" {
+			hunks[file] = []lineRange{{start: 1, end: math.MaxInt}}
+		}
+	}
+	for file, ranges := range hunks {
+		result[filepath.Join(root, filepath.FromSlash(file))] = ranges
+	}
+	return nil
+}
+
+// markChanges colors the classes whose type declaration or methods changed and puts the changed methods on the same
+// background. Classes that already have a color get a border in the accent color instead. It returns the changed
+// classes.
+func markChanges(d *Diagram, idx *sourceIndex, changes fileChanges) map[*Class]bool {
+	result := map[*Class]bool{}
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		changed := changes.touches(idx.fset, t.spec)
+		for _, method := range t.methods {
+			if !changes.touches(idx.fset, method.decl) {
+				continue
+			}
+			changed = true
+			for i, member := range c.Members {
+				if isMethod(member) && memberName(member) == method.decl.Name.Name {
+					c.Members[i] = markMember(member, changedSinceColor)
+				}
+			}
+		}
+		if !changed {
+			continue
+		}
+		result[c] = true
+		if hasColor(c) {
+			highlightClass(c, changedSinceColor)
+		} else {
+			addColor(c, changedSinceColor)
+		}
+	}
+	return result
+}
+
+// limitToChanges removes the classes that did not change and are not directly related to a class that did
+func limitToChanges(d *Diagram, changed map[*Class]bool) {
+	classes := map[string]*Class{}
+	for _, c := range d.AllClasses() {
+		classes[c.FullName()] = c
+		classes[c.Ref()] = c
+	}
+	kept := map[*Class]bool{}
+	for c := range changed {
+		kept[c] = true
+	}
+	for _, e := range d.Edges {
+		from, to := classes[e.From], classes[e.To]
+		if changed[from] && to != nil {
+			kept[to] = true
+		}
+		if changed[to] && from != nil {
+			kept[from] = true
+		}
+	}
+	for _, c := range d.AllClasses() {
+		if !kept[c] {
+			d.RemoveClass(c)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestParseDiffHunks(t *testing.T) {
+	diff := `diff --git a/shop/shop.go b/shop/shop.go
+index 1111111..2222222 100644
+--- a/shop/shop.go
++++ b/shop/shop.go
+@@ -3,0 +4,2 @@ type Order struct {
++	Customer *Customer
++	Total    int64
+@@ -9 +11 @@ func (o *Order) Total() int64 {
+-	return 0
++	return o.Total
+@@ -20,3 +21,0 @@ func (o *Order) Cancel() {
+diff --git a/shop/legacy.go b/shop/legacy.go
+deleted file mode 100644
+--- a/shop/legacy.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+`
+	result := parseDiffHunks(diff)
+	expected := []lineRange{{start: 4, end: 5}, {start: 11, end: 11}, {start: 21, end: 21}}
+	if len(result) != 1 || len(result["shop/shop.go"]) != len(expected) {
+		t.Fatalf("parseDiffHunks() = %v, want %v for shop/shop.go", result, expected)
+	}
+	for i, want := range expected {
+		if r := result["shop/shop.go"][i]; r != want {
+			t.Errorf("range %d = %+v, want %+v", i, r, want)
+		}
+	}
+}
+
+func TestParseDiffHunksFileNames(t *testing.T) {
+	diff := "+++ b/shop/my order.go\t\n@@ -1 +1 @@\n+++ \"b/shop/\\303\\274.go\"\n@@ -2 +2 @@\n"
+	result := parseDiffHunks(diff)
+	for _, file := range []string{"shop/my order.go", "shop/\u00fc.go"} {
+		if len(result[file]) != 1 {
+			t.Errorf("parseDiffHunks() = %v, want a range for %q", result, file)
+		}
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	roots := []string{}
+	for _, name := range []string{"shop/order.go", "billing/invoice.go"} {
+		root := writeFiles(t, map[string]string{name: "package " + filepath.Dir(name) + "\n"})
+		git := func(args ...string) {
+			t.Helper()
+			if _, err := runGit(root, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("init", "--quiet")
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial")
+		roots = append(roots, root)
+	}
+	files := map[string]string{
+		filepath.Join(roots[0], "shop", "my order.go"):   "package shop\n\ntype Order struct{}\n",
+		filepath.Join(roots[1], "billing", "invoice.go"): "package billing\n\ntype Invoice struct{}\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := changedLines([]string{filepath.Join(roots[0], "shop"), roots[1], roots[0]}, "HEAD")
+	if err != nil {
+		t.Fatalf("changedLines() error = %v", err)
+	}
+	if len(changes) != len(files) {
+		t.Errorf("changedLines() = %v, want the changes of both repositories", changes)
+	}
+	for path := range files {
+		if len(changes.lines(path)) == 0 {
+			t.Errorf("changedLines() = %v, want changes of %s", changes, path)
+		}
+	}
+}
+
+func TestMarkChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := writeFiles(t, map[string]string{"go.mod": "module example.com/shop\n", "shop/shop.go": newShop})
+	git := func(args ...string) {
+		t.Helper()
+		if _, err := runGit(root, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	git("add", "-A")
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial")
+	changed := strings.Replace(newShop, "return int64(o.Price)", "return o.Price", 1)
+	files := map[string]string{
+		"shop/shop.go":    changed,
+		"shop/invoice.go": "package shop\n\ntype Invoice struct {\n\tOrder *Order\n}\n",
+		"shop/tax.go":     "package shop\n\ntype Tax struct{}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := changedLines([]string{filepath.Join(root, "shop")}, "HEAD")
+	if err != nil {
+		t.Fatalf("changedLines() error = %v", err)
+	}
+	dirs := []string{filepath.Join(root, "shop")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	_ = result.SetRenderingOptions(map[goplantuml.RenderingOption]any{goplantuml.RenderAggregations: true})
+	d := ParseDiagram(result.Render())
+	marked := markChanges(d, loadSourceIndex(sources, dirs), changes)
+
+	extras := map[string]string{
+		"shop.Order":    changedSinceColor,
+		"shop.Invoice":  changedSinceColor,
+		"shop.Tax":      changedSinceColor,
+		"shop.Customer": "",
+	}
+	for name, want := range extras {
+		if c := d.FindClass(name); c == nil || c.Extra != want || marked[c] != (want != "") {
+			t.Errorf("Extra of %s = %+v, want %q", name, c, want)
+		}
+	}
+	members := d.FindClass("shop.Order").Members
+	if !containsString(members, "+ <back:#Gold>Total() int64</back>") || !containsString(members, "+ ID int") {
+		t.Errorf("Members = %q, want only Total marked", members)
+	}
+}
+
+func TestLimitToChanges(t *testing.T) {
+	d := ParseDiagram(cyclicDiagram)
+	limitToChanges(d, map[*Class]bool{d.FindClass("billing.Tax"): true})
+
+	names := []string{}
+	for _, c := range d.AllClasses() {
+		names = append(names, c.FullName())
+	}
+	if expected := []string{"billing.Invoice", "billing.Tax"}; !equalStrings(names, expected) {
+		t.Errorf("classes = %q, want %q", names, expected)
+	}
+	edges := []string{}
+	for _, e := range d.Edges {
+		edges = append(edges, e.String())
+	}
+	expected := []string{`"billing.Invoice" o-- "billing.Tax"`, `"billing.Tax" ..> "time.Time"`}
+	if !equalStrings(edges, expected) {
+		t.Errorf("edges = %q, want %q", edges, expected)
+	}
+}
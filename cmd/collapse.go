@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// collapsedStereotypes mark the class a collapsed package is drawn as
+var collapsedStereotypes = []string{" (P,LightSteelBlue) ", "package"}
+
+// typeCountLine matches the body line of a collapsed package, e.g. "12 exported types"
+var typeCountLine = regexp.MustCompile(`^\d+ exported types?$`)
+
+// typeCount returns the body line of a collapsed package with n exported types
+func typeCount(n int) string {
+	if n == 1 {
+		return "1 exported type"
+	}
+	return fmt.Sprintf("%d exported types", n)
+}
+
+// packageCollapser decides which namespaces are collapsed: the listed ones, given by name, by dotted path or by
+// slash separated path, and the ones nested deeper than depth if it is set
+type packageCollapser struct {
+	names []string
+	depth int
+}
+
+// enabled reports whether any namespace can be collapsed
+func (p packageCollapser) enabled() bool {
+	return len(p.names) > 0 || p.depth > 0
+}
+
+// collapses reports whether the namespace at the given nesting level, starting at 1, is collapsed
+func (p packageCollapser) collapses(ns *Namespace, level int) bool {
+	if ns.Group {
+		return false
+	}
+	if p.depth > 0 && level > p.depth {
+		return true
+	}
+	for _, name := range p.names {
+		if name == ns.Name || name == ns.Path || strings.ReplaceAll(name, "/", ".") == ns.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// collapsePackages replaces collapsed namespaces, together with their nested namespaces, by a single class showing
+// the number of exported types they declare. Relationships of their classes are moved to that class, relationships
+// that end up connecting the same classes with the same arrow are merged into one labelled with their count, and
+// relationships inside a collapsed namespace are dropped.
+func collapsePackages(d *Diagram, p packageCollapser) {
+	targets := map[string]string{} // class names and aliases to the path of their collapsed namespace
+	prefixes := []string{}
+	var collapse func(namespaces []*Namespace, parent *Namespace, level int) []*Namespace
+	collapse = func(namespaces []*Namespace, parent *Namespace, level int) []*Namespace {
+		kept := []*Namespace{}
+		for _, ns := range namespaces {
+			if !p.collapses(ns, level) {
+				ns.Children = collapse(ns.Children, ns, level+1)
+				kept = append(kept, ns)
+				continue
+			}
+			exported := 0
+			for _, c := range (&Diagram{Namespaces: []*Namespace{ns}}).AllClasses() {
+				targets[c.FullName()] = ns.Path
+				targets[c.Ref()] = ns.Path
+				if token.IsExported(c.Name) {
+					exported++
+				}
+			}
+			prefixes = append(prefixes, ns.Path)
+			node := &Class{
+				Kind:        "class",
+				Name:        ns.Name,
+				Stereotypes: append([]string{}, collapsedStereotypes...),
+				Members:     []string{typeCount(exported), ""},
+			}
+			if parent != nil {
+				node.Namespace = parent.Path
+				parent.Classes = append(parent.Classes, node)
+			} else {
+				d.Classes = append(d.Classes, node)
+			}
+		}
+		return kept
+	}
+	d.Namespaces = collapse(d.Namespaces, nil, 1)
+	if len(prefixes) == 0 {
+		return
+	}
+
+	target := func(name string) (string, bool) {
+		if path, ok := targets[name]; ok {
+			return path, true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix+".") {
+				return prefix, true
+			}
+		}
+		return name, false
+	}
+	d.Edges = mergeEdges(d.Edges, target)
+
+	notes := []*Note{}
+	for _, n := range d.Notes {
+		class, _, _ := strings.Cut(n.Target, "::")
+		if _, collapsed := target(class); !collapsed {
+			notes = append(notes, n)
+		}
+	}
+	d.Notes = notes
+}
+
+// mergeEdges moves the ends of relationships to the names target returns for them. Relationships that end up
+// connecting the same names with the same arrow are merged into one labelled with their count, relationships of
+// which both ends are moved to the same name are dropped. The relationships passed in are not changed.
+func mergeEdges(edges []*Edge, target func(name string) (string, bool)) []*Edge {
+	result := []*Edge{}
+	merged := map[Edge]*Edge{}
+	counts := map[*Edge]int{}
+	for _, e := range edges {
+		from, fromMoved := target(e.From)
+		to, toMoved := target(e.To)
+		if !fromMoved && !toMoved {
+			result = append(result, e)
+			continue
+		}
+		if from == to {
+			continue
+		}
+		key := Edge{From: from, Arrow: e.Arrow, To: to}
+		if first, ok := merged[key]; ok {
+			counts[first]++
+			continue
+		}
+		moved := *e
+		moved.From, moved.To = from, to
+		merged[key] = &moved
+		counts[&moved] = 1
+		result = append(result, &moved)
+	}
+	for e, count := range counts {
+		if count > 1 {
+			e.FromLabel, e.ToLabel = "", ""
+			e.Label = fmt.Sprintf("x%d", count)
+		}
+	}
+	return result
+}
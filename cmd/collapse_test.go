@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// nestedDiagram has a namespace nested in another one and relationships into, out of and inside of both
+const nestedDiagram = `@startuml
+namespace app {
+    class "Handler" << (S,Aquamarine) >> {
+        + Repo store.Repository
+
+    }
+}
+namespace store {
+    class "User" << (S,Aquamarine) >> {
+        + ID int
+
+    }
+    interface "Repository" {
+        + Find(id int) *User
+
+    }
+    class "cache" << (S,Aquamarine) >> {
+    }
+    namespace sql {
+        class "DB" << (S,Aquamarine) >> {
+            + Owner *store.User
+
+        }
+    }
+}
+
+"store.Repository" <|-- "store.sql.DB"
+"app.Handler" o-- "store.Repository" : Repo
+"app.Handler" "1" o-- "0..1" "store.User" : Last
+"app.Handler" o-- "store.sql.DB" : db
+"store.sql.DB" o-- "store.User" : Owner
+@enduml
+`
+
+func TestCollapsePackages(t *testing.T) {
+	tests := []struct {
+		name      string
+		collapser packageCollapser
+		nodes     map[string]string
+		edges     []string
+	}{
+		{
+			name:      "by name",
+			collapser: packageCollapser{names: []string{"store"}},
+			nodes:     map[string]string{"store": "3 exported types"},
+			edges:     []string{`"app.Handler" o-- "store" : x3`},
+		},
+		{
+			name:      "by path",
+			collapser: packageCollapser{names: []string{"store/sql"}},
+			nodes:     map[string]string{"store.sql": "1 exported type"},
+			edges: []string{
+				`"store.Repository" <|-- "store.sql"`,
+				`"app.Handler" o-- "store.Repository" : Repo`,
+				`"app.Handler" "1" o-- "0..1" "store.User" : Last`,
+				`"app.Handler" o-- "store.sql" : db`,
+				`"store.sql" o-- "store.User" : Owner`,
+			},
+		},
+		{
+			name:      "by depth",
+			collapser: packageCollapser{depth: 1},
+			nodes:     map[string]string{"store.sql": "1 exported type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := ParseDiagram(nestedDiagram)
+			d.Notes = []*Note{{Position: "right", Target: "store.User", Text: "A user."}}
+			collapsePackages(d, tt.collapser)
+
+			for name, count := range tt.nodes {
+				c := d.FindClass(name)
+				if c == nil {
+					t.Fatalf("collapsed package %s not found in\n%s", name, d.Render())
+				}
+				if !equalStrings(c.Members, []string{count, ""}) {
+					t.Errorf("Members of %s = %q, want %q", name, c.Members, count)
+				}
+				if !equalStrings(plainStereotypes(c), []string{"package"}) {
+					t.Errorf("Stereotypes of %s = %q, want package", name, c.Stereotypes)
+				}
+			}
+			if tt.edges != nil {
+				edges := []string{}
+				for _, e := range d.Edges {
+					edges = append(edges, e.String())
+				}
+				if !equalStrings(edges, tt.edges) {
+					t.Errorf("Edges = %q, want %q", edges, tt.edges)
+				}
+			}
+			if collapsed := len(d.Notes) == 0; collapsed != (d.FindClass("store.User") == nil) {
+				t.Errorf("Notes = %d, want the note of store.User only if it is shown", len(d.Notes))
+			}
+		})
+	}
+}
+
+func TestConvertCollapsedPackage(t *testing.T) {
+	d := ParseDiagram(nestedDiagram)
+	collapsePackages(d, packageCollapser{names: []string{"store"}})
+	result, err := ConvertToMermaid(d.Render())
+	if err != nil {
+		t.Fatalf("ConvertToMermaid() error = %v", err)
+	}
+	for _, expected := range []string{"class store {", "<<package>>", "3 exported types", "Handler o-- store : x3"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ConvertToMermaid() = %s\nwant it to contain %q", result, expected)
+		}
+	}
+}
@@ -16,6 +16,16 @@ func TestConvertToMermaid(t *testing.T) {
 			input:    "",
 			expected: `classDiagram`,
 		},
+		{
+			name: "unquoted relationships",
+			input: `@startuml
+A <|-- B
+ClassA *-- ClassB
+@enduml`,
+			expected: `classDiagram
+    B --|> A
+    ClassA *-- ClassB`,
+		},
 		{
 			name: "simple class",
 			input: `@startuml
@@ -258,6 +268,22 @@ namespace impl {
     }
     UserHandler --|> Handler`,
 		},
+		{
+			name: "truncated members",
+			input: `@startuml
+class "User" << (S,Aquamarine) >> {
+    + ID int
+    ... 2 more
+
+}
+@enduml`,
+			expected: `classDiagram
+    class User {
+        <<struct>>
+        +ID int
+        ... 2 more
+    }`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -505,6 +531,11 @@ func TestConvertFieldOrMethod(t *testing.T) {
 			input:    "- internal <font color=red>chan</font> <font color=blue>struct</font>{}",
 			expected: "-internal <font color=red>chan struct{}",
 		},
+		{
+			name:     "field with struct tags",
+			input:    `+ Name string <<json:"name,omitempty" db:"user_name">>`,
+			expected: "+Name string json:name,omitempty db:user_name",
+		},
 		{
 			name:     "empty input",
 			input:    "",
@@ -567,6 +598,21 @@ func TestConvertRelationshipWithMapping(t *testing.T) {
 			input:    `"api.Handler" <|-- "impl.UserHandler"`,
 			expected: "UserHandler --|> Handler",
 		},
+		{
+			name:     "aggregation with multiplicities and label",
+			input:    `"example.User" "1" o-- "*" "example.Profile" : Profiles`,
+			expected: `User "1" o-- "*" Profile : Profiles`,
+		},
+		{
+			name:     "inheritance with connection label",
+			input:    `"example.UserService" <|-- "implements""example.DatabaseUserService"`,
+			expected: "DatabaseUserService --|> UserService : implements",
+		},
+		{
+			name:     "alias is not converted",
+			input:    `"example.User" #.. "alias of""example.Profile"`,
+			expected: "",
+		},
 		{
 			name:     "unknown classes fallback",
 			input:    `"unknown.ClassA" <|-- "unknown.ClassB"`,
@@ -577,6 +623,21 @@ func TestConvertRelationshipWithMapping(t *testing.T) {
 			input:    "",
 			expected: "",
 		},
+		{
+			name:     "unquoted inheritance",
+			input:    "A <|-- B",
+			expected: "B --|> A",
+		},
+		{
+			name:     "unquoted composition",
+			input:    "ClassA *-- ClassB",
+			expected: "ClassA *-- ClassB",
+		},
+		{
+			name:     "unquoted mapped classes with label",
+			input:    "example.User o-- example.Profile : Profiles",
+			expected: "User o-- Profile : Profiles",
+		},
 		{
 			name:     "malformed relationship",
 			input:    "not a relationship",
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Graphs cycles are searched in
+const (
+	cycleTypes    = "types"
+	cyclePackages = "packages"
+)
+
+// cycleColor is the color -highlight-cycles draws the classes and relationships of cycles in
+const cycleColor = "#red"
+
+// dependencyGraph maps every node to the nodes it depends on and the relationships it depends on them through
+type dependencyGraph map[string]map[string][]*Edge
+
+// add records a dependency of from on to through the relationship
+func (g dependencyGraph) add(from, to string, e *Edge) {
+	if g[from] == nil {
+		g[from] = map[string][]*Edge{}
+	}
+	g[from][to] = append(g[from][to], e)
+}
+
+// link records a dependency of from on to that no relationship draws, e.g. an import of a package
+func (g dependencyGraph) link(from, to string) {
+	if g[from] == nil {
+		g[from] = map[string][]*Edge{}
+	}
+	if _, ok := g[from][to]; !ok {
+		g[from][to] = nil
+	}
+}
+
+// edges returns the relationships between the nodes, in the order of the nodes
+func (g dependencyGraph) edges(nodes []string) []*Edge {
+	result := []*Edge{}
+	for _, from := range nodes {
+		for _, to := range nodes {
+			result = append(result, g[from][to]...)
+		}
+	}
+	return result
+}
+
+// stronglyConnected returns the strongly connected components of the graph with more than one node, found with
+// Tarjan's algorithm. The nodes of a component are sorted, components by their first node.
+func stronglyConnected(g dependencyGraph) [][]string {
+	nodes := []string{}
+	for from, targets := range g {
+		nodes = append(nodes, from)
+		for to := range targets {
+			nodes = append(nodes, to)
+		}
+	}
+	sort.Strings(nodes)
+
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	result := [][]string{}
+	var connect func(node string)
+	connect = func(node string) {
+		index[node] = len(index)
+		low[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+		targets := []string{}
+		for to := range g[node] {
+			targets = append(targets, to)
+		}
+		sort.Strings(targets)
+		for _, to := range targets {
+			if _, visited := index[to]; !visited {
+				connect(to)
+				low[node] = min(low[node], low[to])
+			} else if onStack[to] {
+				low[node] = min(low[node], index[to])
+			}
+		}
+		if low[node] != index[node] {
+			return
+		}
+		component := []string{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			result = append(result, component)
+		}
+	}
+	for _, node := range nodes {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][0] < result[j][0] })
+	return result
+}
+
+// cycle is a group of classes or namespaces that depend on each other
+type cycle struct {
+	graph string   // cycleTypes or cyclePackages
+	nodes []string // full names of classes or namespace paths
+	edges []*Edge
+}
+
+// findCycles returns the cycles between the classes of the diagram, followed by the cycles between their
+// namespaces. Relationships to types that are not part of the diagram are ignored. Namespaces also depend on the
+// packages they import if the index is not nil.
+func findCycles(d *Diagram, idx *sourceIndex) []*cycle {
+	packages := newPackageGraph(d, idx)
+	types := dependencyGraph{}
+	for _, e := range d.Edges {
+		from, to := dependency(e)
+		fromClass, toClass := packages.classes[from], packages.classes[to]
+		if fromClass != nil && toClass != nil && fromClass != toClass {
+			types.add(fromClass.FullName(), toClass.FullName(), e)
+		}
+	}
+	result := []*cycle{}
+	for _, nodes := range stronglyConnected(types) {
+		result = append(result, &cycle{graph: cycleTypes, nodes: nodes, edges: types.edges(nodes)})
+	}
+	for _, nodes := range packages.cycles() {
+		result = append(result, &cycle{graph: cyclePackages, nodes: nodes, edges: packages.edges.edges(nodes)})
+	}
+	return result
+}
+
+// reportCycles writes one line per cycle
+func reportCycles(w io.Writer, cycles []*cycle) {
+	for _, c := range cycles {
+		fmt.Fprintf(w, "cycle between %s %s\n", c.graph, strings.Join(c.nodes, ", "))
+	}
+}
+
+// highlightCycles draws the classes of the diagram the relationships of the cycles connect in red, and the
+// relationships of the diagram between them in the direction of the cycles. Cycles are found in the diagram of all
+// relationships, whose classes are known by the names they are declared with, so relationships the diagram does not
+// draw are left out.
+func highlightCycles(d *Diagram, cycles []*cycle) {
+	declared := map[string]*Class{} // classes by the name in the package they are declared in and by reference
+	drawn := map[string]*Class{}    // classes by the name the relationships of the diagram use
+	for _, c := range d.AllClasses() {
+		declared[c.SourceName()] = c
+		declared[c.Ref()] = c
+		drawn[c.FullName()] = c
+		drawn[c.Ref()] = c
+	}
+	dependencies := map[[2]*Class]bool{}
+	highlighted := map[*Class]bool{}
+	for _, cy := range cycles {
+		for _, e := range cy.edges {
+			from, to := dependency(e)
+			fromClass, toClass := declared[from], declared[to]
+			for _, c := range []*Class{fromClass, toClass} {
+				if c != nil && !highlighted[c] {
+					highlighted[c] = true
+					highlightClass(c, cycleColor)
+				}
+			}
+			if fromClass != nil && toClass != nil {
+				dependencies[[2]*Class{fromClass, toClass}] = true
+			}
+		}
+	}
+	for _, e := range d.Edges {
+		from, to := dependency(e)
+		if dependencies[[2]*Class{drawn[from], drawn[to]}] {
+			e.Arrow = colorArrow(e.Arrow, cycleColor)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// cyclicDiagram has a cycle between the types Order and Customer and, through Customer and Invoice, between the
+// packages shop and billing
+const cyclicDiagram = `@startuml
+namespace shop {
+    class "Order" << (S,Aquamarine) >> #Pink {
+        + Customer *Customer
+    }
+    class "Customer" << (S,Aquamarine) >> {
+        + Orders []*Order
+    }
+}
+namespace billing {
+    class "Invoice" << (S,Aquamarine) >> {
+    }
+    class "Tax" << (S,Aquamarine) >> {
+    }
+}
+
+"shop.Order" o-- "shop.Customer"
+"shop.Customer" o-- "shop.Order"
+"shop.Customer" ..> "billing.Invoice"
+"billing.Invoice" ..> "shop.Customer"
+"billing.Invoice" o-- "billing.Tax"
+"billing.Tax" ..> "time.Time"
+@enduml
+`
+
+func TestStronglyConnected(t *testing.T) {
+	g := dependencyGraph{}
+	for _, pair := range []string{"a b", "b c", "c a", "c d", "d e", "e d", "f f"} {
+		from, to, _ := strings.Cut(pair, " ")
+		g.add(from, to, &Edge{From: from, Arrow: "..>", To: to})
+	}
+	result := stronglyConnected(g)
+	expected := [][]string{{"a", "b", "c"}, {"d", "e"}}
+	if len(result) != len(expected) {
+		t.Fatalf("stronglyConnected() = %q, want %q", result, expected)
+	}
+	for i := range expected {
+		if !equalStrings(result[i], expected[i]) {
+			t.Errorf("component %d = %q, want %q", i, result[i], expected[i])
+		}
+	}
+	if edges := g.edges(result[1]); len(edges) != 2 {
+		t.Errorf("edges() = %d relationships, want 2", len(edges))
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	cycles := findCycles(ParseDiagram(cyclicDiagram), nil)
+	var report bytes.Buffer
+	reportCycles(&report, cycles)
+	expected := "cycle between types billing.Invoice, shop.Customer, shop.Order\n" +
+		"cycle between packages billing, shop\n"
+	if report.String() != expected {
+		t.Errorf("reportCycles() = %q, want %q", report.String(), expected)
+	}
+	if len(cycles) != 2 || len(cycles[0].edges) != 4 || len(cycles[1].edges) != 2 {
+		t.Errorf("findCycles() did not collect the relationships of the cycles")
+	}
+
+	if cycles := findCycles(ParseDiagram(layeredDiagram), nil); len(cycles) != 1 || cycles[0].graph != cyclePackages {
+		t.Errorf("findCycles() = %d cycles, want the one between domain and infra", len(cycles))
+	}
+}
+
+func TestFindCyclesThroughImports(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/shop\n",
+		"shop/order.go": "package shop\n\nimport \"example.com/shop/billing\"\n\ntype Order struct{}\n\n" +
+			"func (o *Order) Bill() { billing.Charge() }\n",
+		"billing/invoice.go": "package billing\n\nimport \"example.com/shop/shop\"\n\ntype Invoice struct{}\n\n" +
+			"func Charge() { _ = shop.Order{} }\n",
+	})
+	dirs := []string{root}
+	sources, err := collectSources(dirs, true, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	d := ParseDiagram(`@startuml
+namespace shop {
+    class "Order" << (S,Aquamarine) >> {
+    }
+}
+namespace billing {
+    class "Invoice" << (S,Aquamarine) >> {
+    }
+}
+@enduml
+`)
+	if cycles := findCycles(d, nil); len(cycles) != 0 {
+		t.Errorf("findCycles() without index = %d cycles, want none", len(cycles))
+	}
+	var report bytes.Buffer
+	reportCycles(&report, findCycles(d, loadSourceIndex(sources, dirs)))
+	if expected := "cycle between packages billing, shop\n"; report.String() != expected {
+		t.Errorf("reportCycles() = %q, want %q", report.String(), expected)
+	}
+}
+
+func TestHighlightCyclesNotDrawn(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"shop/shop.go": "package shop\n\ntype Cart struct {\n\torder *Order\n}\n\n" +
+			"type Order struct {\n\tCart *Cart\n\tItems []Item\n}\n\ntype Item struct{}\n",
+	})
+	dirs := []string{filepath.Join(root, "shop")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	idx := loadSourceIndex(sources, dirs)
+	tests := []struct {
+		name     string
+		ro       map[goplantuml.RenderingOption]any
+		expected []string
+	}{
+		{
+			name: "without aggregations",
+			ro:   map[goplantuml.RenderingOption]any{},
+		},
+		{
+			name: "with aggregations",
+			ro:   map[goplantuml.RenderingOption]any{goplantuml.RenderAggregations: true},
+			expected: []string{
+				`"shop.Order" o-[#red]- "shop.Cart"`,
+				`"shop.Order" o-- "shop.Item"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", root, err)
+			}
+			_ = result.SetRenderingOptions(tt.ro)
+			d := ParseDiagram(result.Render())
+			_ = result.SetRenderingOptions(rulesRenderingOptions(tt.ro))
+			full := ParseDiagram(result.Render())
+			addRuleRelationships(full, nil, false, idx, rulesRenderingOptions(tt.ro))
+
+			cycles := findCycles(full, idx)
+			if len(cycles) != 1 || !equalStrings(cycles[0].nodes, []string{"shop.Cart", "shop.Order"}) {
+				t.Fatalf("findCycles() = %d cycles, want the one between shop.Cart and shop.Order", len(cycles))
+			}
+			highlightCycles(d, cycles)
+			for name, want := range map[string]string{"shop.Cart": "#line:red", "shop.Order": "#line:red", "shop.Item": ""} {
+				if c := d.FindClass(name); c == nil || c.Extra != want {
+					t.Errorf("Extra of %s = %+v, want %q", name, c, want)
+				}
+			}
+			edges := []string{}
+			for _, e := range d.Edges {
+				edges = append(edges, e.String())
+			}
+			if !equalStrings(edges, tt.expected) {
+				t.Errorf("Edges = %q, want %q", edges, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHighlightCycles(t *testing.T) {
+	d := ParseDiagram(cyclicDiagram)
+	highlightCycles(d, findCycles(d, nil))
+
+	extras := map[string]string{
+		"shop.Order":      "#Pink;line:red",
+		"shop.Customer":   "#line:red",
+		"billing.Invoice": "#line:red",
+		"billing.Tax":     "",
+	}
+	for name, want := range extras {
+		if c := d.FindClass(name); c == nil || c.Extra != want {
+			t.Errorf("Extra of %s = %+v, want %q", name, c, want)
+		}
+	}
+	expected := []string{
+		`"shop.Order" o-[#red]- "shop.Customer"`,
+		`"shop.Customer" o-[#red]- "shop.Order"`,
+		`"shop.Customer" .[#red].> "billing.Invoice"`,
+		`"billing.Invoice" .[#red].> "shop.Customer"`,
+		`"billing.Invoice" o-- "billing.Tax"`,
+	}
+	for i, want := range expected {
+		if line := d.Edges[i].String(); line != want {
+			t.Errorf("edge %d = %s, want %s", i, line, want)
+		}
+	}
+
+	addColor(d.FindClass("shop.Customer"), hotspotColor)
+	if extra := d.FindClass("shop.Customer").Extra; extra != hotspotColor+";line:red" {
+		t.Errorf("Extra = %q, want the hotspot color with the red line", extra)
+	}
+
+	mermaid, err := ConvertToMermaid(d.Render())
+	if err != nil {
+		t.Fatalf("ConvertToMermaid() error = %v", err)
+	}
+	for _, style := range []string{
+		"style Order fill:pink,stroke:red",
+		"style Invoice stroke:red",
+		"style Customer fill:lightcoral,stroke:red",
+		"linkStyle 0 stroke:red",
+		"linkStyle 3 stroke:red",
+	} {
+		if !containsString(strings.Split(mermaid, "\n"), "    "+style) {
+			t.Errorf("ConvertToMermaid() = %s\nwant %s", mermaid, style)
+		}
+	}
+	if strings.Contains(mermaid, "linkStyle 4") {
+		t.Errorf("ConvertToMermaid() styled a relationship outside of the cycles:\n%s", mermaid)
+	}
+}
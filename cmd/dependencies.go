@@ -0,0 +1,124 @@
+package main
+
+import (
+	"go/ast"
+	"sort"
+)
+
+const (
+	dependencyArrow = "..>"
+	dependencyLabel = "uses"
+)
+
+// typeParameterNames returns the names of the type parameters a type or a method receiver declares
+func typeParameterNames(t *sourceType, m *sourceMethod) map[string]struct{} {
+	result := map[string]struct{}{}
+	if t.spec.TypeParams != nil {
+		for _, field := range t.spec.TypeParams.List {
+			for _, name := range field.Names {
+				result[name.Name] = struct{}{}
+			}
+		}
+	}
+	if m == nil || len(m.decl.Recv.List) == 0 {
+		return result
+	}
+	expr := m.decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	var indices []ast.Expr
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		indices = e.Indices
+	}
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			result[ident.Name] = struct{}{}
+		}
+	}
+	return result
+}
+
+// dependencies returns the classes a type refers to in the parameters and results of its methods, but not in its
+// fields or embedded types, sorted by name
+func dependencies(c *Class, t *sourceType, r *classResolver) []*Class {
+	resolve := func(file *ast.File, exprs []ast.Expr, params map[string]struct{}) []*Class {
+		result := []*Class{}
+		for _, expr := range exprs {
+			if ident, ok := expr.(*ast.Ident); ok {
+				if _, ok := params[ident.Name]; ok {
+					continue
+				}
+			}
+			if target := r.resolve(c.SourceNamespace(), file, expr); target != nil && target != c {
+				result = append(result, target)
+			}
+		}
+		return result
+	}
+
+	fields := map[*Class]struct{}{}
+	typeParams := typeParameterNames(t, nil)
+	var fieldList, methodList []*ast.Field
+	switch spec := t.spec.Type.(type) {
+	case *ast.StructType:
+		fieldList = spec.Fields.List
+	case *ast.InterfaceType:
+		for _, field := range spec.Methods.List {
+			if _, ok := field.Type.(*ast.FuncType); ok {
+				methodList = append(methodList, field)
+			} else {
+				fieldList = append(fieldList, field)
+			}
+		}
+	}
+	for _, field := range fieldList {
+		for _, target := range resolve(t.file, typeNames(field.Type), typeParams) {
+			fields[target] = struct{}{}
+		}
+	}
+
+	seen := map[*Class]struct{}{}
+	result := []*Class{}
+	add := func(file *ast.File, fn *ast.FuncType, params map[string]struct{}) {
+		for _, target := range resolve(file, typeNames(fn), params) {
+			_, isField := fields[target]
+			_, isSeen := seen[target]
+			if !isField && !isSeen {
+				seen[target] = struct{}{}
+				result = append(result, target)
+			}
+		}
+	}
+	for _, field := range methodList {
+		add(t.file, field.Type.(*ast.FuncType), typeParams)
+	}
+	for _, m := range t.methods {
+		add(m.file, m.decl.Type, typeParameterNames(t, m))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FullName() < result[j].FullName()
+	})
+	return result
+}
+
+// addDependencies draws a dashed relationship from every class to the classes it only uses in method signatures
+func addDependencies(d *Diagram, idx *sourceIndex, labels bool) {
+	r := newClassResolver(d)
+	label := ""
+	if labels {
+		label = dependencyLabel
+	}
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		for _, target := range dependencies(c, t, r) {
+			d.AddEdge(&Edge{From: c.Ref(), Arrow: dependencyArrow, ToLabel: label, To: target.Ref()})
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestAddDependencies(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"app/model/model.go": "package model\n\ntype User struct{}\n\ntype Group struct{}\n",
+		"app/store/store.go": `package store
+
+import (
+	"context"
+
+	m "example.com/app/model"
+)
+
+type Query struct{}
+
+type Store struct {
+	groups []*m.Group
+}
+
+func (s *Store) Find(ctx context.Context, q Query) (*m.User, error) { return nil, nil }
+
+func (s *Store) Groups(m.User) []m.Group { return s.groups }
+
+func (s *Store) Self() *Store { return s }
+
+type Finder interface {
+	Find(ctx context.Context, q Query) (*m.User, error)
+}
+
+type Cache[T any] struct{}
+
+func (c *Cache[T]) Get(key Query) T {
+	var zero T
+	return zero
+}
+`,
+	})
+
+	sources, err := collectSources([]string{root}, true, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth([]string{root}, []string{}, true, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	d.RemoveEdges(func(*Edge) bool { return true })
+	addDependencies(d, loadSourceIndex(sources, []string{root}), false)
+
+	expected := []string{
+		`"store.Store" ..> "model.User"`,
+		`"store.Store" ..> "store.Query"`,
+		`"store.Finder" ..> "model.User"`,
+		`"store.Finder" ..> "store.Query"`,
+		`"Cache_generic_T" ..> "store.Query"`,
+	}
+	edges := []string{}
+	for _, e := range d.Edges {
+		edges = append(edges, e.String())
+	}
+	if !equalStrings(edges, expected) {
+		t.Errorf("Edges = %q, want %q", edges, expected)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"go/ast"
+	"strings"
+)
+
+const (
+	deprecatedMarker     = "Deprecated: "
+	deprecatedStereotype = "deprecated"
+	deprecatedColor      = "#LightGrey"
+)
+
+// isDeprecated reports whether a doc comment has a paragraph starting with "Deprecated: ", the convention go doc
+// and gopls follow
+func isDeprecated(groups ...*ast.CommentGroup) bool {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, paragraph := range strings.Split(group.Text(), "\n\n") {
+			if strings.HasPrefix(paragraph, deprecatedMarker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// strikeThrough returns a member line with its text struck through, keeping the visibility in front
+func strikeThrough(member string) string {
+	text := strings.TrimLeft(member, "+-#~ ")
+	return member[:len(member)-len(text)] + "<s>" + text + "</s>"
+}
+
+// applyDeprecations marks deprecated types with a stereotype and a grey background and strikes through deprecated
+// fields and methods. If hide is set, they are removed from the diagram instead.
+func applyDeprecations(d *Diagram, idx *sourceIndex, hide bool) {
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		if isDeprecated(t.doc) {
+			if hide {
+				d.RemoveClass(c)
+				continue
+			}
+			c.Stereotypes = append(c.Stereotypes, deprecatedStereotype)
+			if !hasColor(c) {
+				c.Extra = strings.TrimSpace(c.Extra + " " + deprecatedColor)
+			}
+		}
+
+		deprecated := map[string]bool{}
+		for name, groups := range memberComments(t) {
+			if isDeprecated(groups...) {
+				deprecated[name] = true
+			}
+		}
+		if hide {
+			removeMembers(d, c, deprecated)
+			continue
+		}
+		for i, m := range c.Members {
+			if deprecated[memberName(m)] {
+				c.Members[i] = strikeThrough(m) + " <<" + deprecatedStereotype + ">>"
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// deprecatedSource declares deprecated types, fields and methods
+const deprecatedSource = `package store
+
+// Store keeps users.
+type Store struct {
+	Users []*User
+	// Deprecated: use Users.
+	Legacy []*User
+}
+
+// Get returns a user.
+//
+// Deprecated: use Find.
+func (s *Store) Get(id int) *User { return nil }
+
+// Find returns a user. It is not Deprecated: the word is not at the start of a paragraph.
+func (s *Store) Find(id int) *User { return nil }
+
+// User is a user.
+//
+// Deprecated: use model.User instead.
+type User struct{}
+`
+
+// deprecatedDiagram renders the diagram of deprecatedSource with the deprecations applied
+func deprecatedDiagram(t *testing.T, hide bool) *Diagram {
+	t.Helper()
+	root := writeFiles(t, map[string]string{"store/store.go": deprecatedSource})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	if !sources.deprecated {
+		t.Fatalf("collectSources() found no deprecations")
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	applyDeprecations(d, loadSourceIndex(sources, dirs), hide)
+	return d
+}
+
+func TestApplyDeprecations(t *testing.T) {
+	tests := []struct {
+		name     string
+		hide     bool
+		user     bool
+		members  []string
+		excluded []string
+	}{
+		{
+			name: "marked",
+			user: true,
+			members: []string{
+				"+ <s>Legacy []*User</s> <<deprecated>>",
+				"+ <s>Get(id int) *User</s> <<deprecated>>",
+				"+ Find(id int) *User",
+				"+ Users []*User",
+			},
+		},
+		{
+			name:     "hidden",
+			hide:     true,
+			members:  []string{"+ Users []*User", "+ Find(id int) *User"},
+			excluded: []string{"Legacy", "Get"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := deprecatedDiagram(t, tt.hide)
+
+			user := d.FindClass("store.User")
+			if (user != nil) != tt.user {
+				t.Fatalf("store.User drawn = %t, want %t", user != nil, tt.user)
+			}
+			if user != nil {
+				if !containsString(user.Stereotypes, deprecatedStereotype) || user.Extra != deprecatedColor {
+					t.Errorf("store.User = %q, want it marked as deprecated", user.String())
+				}
+			}
+
+			store := d.FindClass("store.Store")
+			if containsString(store.Stereotypes, deprecatedStereotype) {
+				t.Errorf("store.Store is marked as deprecated")
+			}
+			for _, member := range tt.members {
+				if !containsString(store.Members, member) {
+					t.Errorf("Members = %q, want %q", store.Members, member)
+				}
+			}
+			for _, excluded := range tt.excluded {
+				if strings.Contains(strings.Join(store.Members, "\n"), excluded) {
+					t.Errorf("Members = %q, want %s hidden", store.Members, excluded)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertToMermaidDeprecated(t *testing.T) {
+	result, err := ConvertToMermaid(deprecatedDiagram(t, false).Render())
+	if err != nil {
+		t.Fatalf("ConvertToMermaid() error = %v", err)
+	}
+	for _, expected := range []string{
+		"class User {\n        <<deprecated>>",
+		"+Get(id int) *User deprecated",
+		"style User fill:lightgrey",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ConvertToMermaid() is missing %q:\n%s", expected, result)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// indent is the indentation goplantuml uses for every nesting level
+const indent = "    "
+
+// Diagram is a PlantUML class diagram rendered by goplantuml, parsed so that go2uml can change its classes and
+// relationships before it is written or converted to Mermaid. Render returns the same layout goplantuml uses.
+type Diagram struct {
+	Header     []string     // lines between @startuml and the first namespace, e.g. title and legend
+	Namespaces []*Namespace // one namespace per Go package, nested like the directories
+	Classes    []*Class     // classes outside of any namespace
+	Edges      []*Edge      // relationships in the order goplantuml rendered them
+	Notes      []*Note      // notes attached to classes, rendered after the relationships
+	Footer     []string     // lines after the relationships, e.g. hide fields
+}
+
+// Namespace is a goplantuml namespace. Path is the dotted name used in relationships, e.g. "cmd.goplantuml".
+// Groups are namespaces that do not stand for a Go package, they are rendered as PlantUML packages.
+type Namespace struct {
+	Name     string
+	Path     string
+	Group    bool
+	Classes  []*Class
+	Children []*Namespace
+}
+
+// Class is a class, interface or type parameter declaration of the diagram
+type Class struct {
+	Kind        string   // "class" or "interface"
+	Name        string   // the quoted name of the declaration
+	Alias       string   // the name after "as", used by goplantuml for generic and renamed classes
+	Stereotypes []string // the text between << and >> of every stereotype, spacing included
+	Extra       string   // anything else between the stereotypes and the opening brace, e.g. a color
+	Members     []string // body lines without indentation, blank lines included
+	Namespace   string   // Path of the enclosing namespace, empty for classes outside of namespaces
+	Package     string   // Path of the namespace of the declaring package once a group directive moved the class
+}
+
+// Edge is a relationship line such as "example.UserService" <|-- "example.DatabaseUserService"
+type Edge struct {
+	From      string // left class, without quotes
+	FromLabel string // quoted text next to the left class, e.g. a multiplicity
+	Arrow     string // e.g. <|--, *--, o--, #.., <--, ..>
+	ToLabel   string // quoted text next to the right class, goplantuml puts connection labels here
+	To        string // right class, without quotes
+	Label     string // text after the colon
+}
+
+// Note is a note attached to a class
+type Note struct {
+	Position string // left, right, top or bottom
+	Target   string // the reference of the class, see Class.Ref
+	Text     string // may span several lines
+}
+
+// FullName returns the name relationships use to refer to the class
+func (c *Class) FullName() string {
+	if c.Namespace == "" {
+		return c.Name
+	}
+	return c.Namespace + "." + c.Name
+}
+
+// SourceNamespace returns the path of the namespace of the package the class is declared in, which is its Namespace
+// unless a //go2uml:group directive moved it
+func (c *Class) SourceNamespace() string {
+	if c.Package != "" {
+		return c.Package
+	}
+	return c.Namespace
+}
+
+// SourceName returns the full name of the class in the package it is declared in
+func (c *Class) SourceName() string {
+	if c.SourceNamespace() == "" {
+		return c.Name
+	}
+	return c.SourceNamespace() + "." + c.Name
+}
+
+// Ref returns the name PlantUML knows the class by, which is the alias of generic classes and the full name otherwise
+func (c *Class) Ref() string {
+	if c.Alias != "" {
+		return c.Alias
+	}
+	return c.FullName()
+}
+
+// hasColor reports whether a class declaration already sets a color, e.g. from a //go2uml:color directive. A border
+// color alone, like #line:red of -highlight-cycles, does not count.
+func hasColor(c *Class) bool {
+	for _, field := range strings.Fields(c.Extra) {
+		if strings.HasPrefix(field, "#") && !strings.HasPrefix(field, "#line:") {
+			return true
+		}
+	}
+	return false
+}
+
+// addColor sets the background of a class declaration, keeping a border color it already has
+func addColor(c *Class, color string) {
+	fields := strings.Fields(c.Extra)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "#line:") {
+			fields[i] = color + ";" + strings.TrimPrefix(field, "#")
+			c.Extra = strings.Join(fields, " ")
+			return
+		}
+	}
+	c.Extra = strings.TrimSpace(c.Extra + " " + color)
+}
+
+// highlightClass draws the border of a class in the color, keeping its background
+func highlightClass(c *Class, color string) {
+	line := "line:" + strings.TrimPrefix(color, "#")
+	fields := strings.Fields(c.Extra)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "#") {
+			if !strings.Contains(field, "line:") {
+				fields[i] = field + ";" + line
+			}
+			c.Extra = strings.Join(fields, " ")
+			return
+		}
+	}
+	c.Extra = strings.TrimSpace(c.Extra + " #" + line)
+}
+
+// ParseDiagram parses the PlantUML rendered by goplantuml
+func ParseDiagram(plantUML string) *Diagram {
+	d := &Diagram{}
+	var stack []*Namespace
+	var current *Class
+	inLegend := false
+	seenBody := false
+
+	for _, raw := range strings.Split(plantUML, "\n") {
+		line := strings.TrimSpace(raw)
+		switch {
+		case current != nil:
+			if line == "}" {
+				current = nil
+				continue
+			}
+			current.Members = append(current.Members, line)
+		case inLegend:
+			d.Header = append(d.Header, line)
+			inLegend = line != "end legend"
+		case line == "@startuml" || line == "@enduml":
+		case line == "legend" || strings.HasPrefix(line, "legend "):
+			d.Header = append(d.Header, line)
+			inLegend = true
+		case (strings.HasPrefix(line, "namespace ") || strings.HasPrefix(line, "package ")) &&
+			strings.HasSuffix(line, "{"):
+			keyword, name, _ := strings.Cut(strings.TrimSpace(strings.TrimSuffix(line, "{")), " ")
+			name = strings.TrimSpace(name)
+			ns := &Namespace{Name: name, Path: name, Group: keyword == "package"}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				ns.Path = parent.Path + "." + name
+				parent.Children = append(parent.Children, ns)
+			} else {
+				d.Namespaces = append(d.Namespaces, ns)
+			}
+			stack = append(stack, ns)
+			seenBody = true
+		case line == "}" && len(stack) > 0:
+			stack = stack[:len(stack)-1]
+		case isClassDeclaration(line):
+			current = parseClassDeclaration(line)
+			if len(stack) > 0 {
+				ns := stack[len(stack)-1]
+				current.Namespace = ns.Path
+				ns.Classes = append(ns.Classes, current)
+			} else {
+				d.Classes = append(d.Classes, current)
+			}
+			seenBody = true
+			if strings.HasSuffix(line, "}") {
+				current = nil
+			}
+		case line == "":
+		default:
+			if edge := ParseEdge(line); edge != nil {
+				d.Edges = append(d.Edges, edge)
+				seenBody = true
+			} else if seenBody {
+				d.Footer = append(d.Footer, line)
+			} else {
+				d.Header = append(d.Header, line)
+			}
+		}
+	}
+	return d
+}
+
+// isClassDeclaration reports whether the line opens a class or interface body
+func isClassDeclaration(line string) bool {
+	return (strings.HasPrefix(line, "class ") || strings.HasPrefix(line, "interface ")) &&
+		strings.Contains(line, "{")
+}
+
+// parseClassDeclaration parses lines like class "ResponseBuilder" as ResponseBuilder_generic_D_I <<[D, I]>> {
+func parseClassDeclaration(line string) *Class {
+	kind, rest, _ := strings.Cut(line, " ")
+	c := &Class{Kind: kind}
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, `"`) {
+		if end := strings.Index(rest[1:], `"`); end >= 0 {
+			c.Name = rest[1 : end+1]
+			rest = rest[end+2:]
+		}
+	} else {
+		c.Name, rest, _ = strings.Cut(rest, " ")
+	}
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "as ") {
+		c.Alias, rest, _ = strings.Cut(strings.TrimSpace(rest[3:]), " ")
+	}
+	if brace := strings.LastIndex(rest, "{"); brace >= 0 {
+		rest = rest[:brace]
+	}
+	for {
+		rest = strings.TrimSpace(rest)
+		start := strings.Index(rest, "<<")
+		end := strings.Index(rest, ">>")
+		if start < 0 || end < start {
+			break
+		}
+		c.Stereotypes = append(c.Stereotypes, rest[start+2:end])
+		rest = rest[:start] + rest[end+2:]
+	}
+	c.Extra = strings.TrimSpace(rest)
+	return c
+}
+
+// ParseEdge parses a relationship line. It returns nil if the line is not a relationship.
+func ParseEdge(line string) *Edge {
+	line = strings.TrimSpace(line)
+	label := ""
+	quoted := []string{}
+	arrowAt := -1
+	arrow := ""
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == ' ':
+			i++
+		case line[i] == '"':
+			end := strings.Index(line[i+1:], `"`)
+			if end < 0 {
+				return nil
+			}
+			quoted = append(quoted, line[i+1:i+1+end])
+			i += end + 2
+		case line[i] == ':' && arrow != "":
+			label = strings.TrimSpace(line[i+1:])
+			i = len(line)
+		default:
+			end := strings.IndexAny(line[i:], ` "`)
+			if end < 0 {
+				end = len(line) - i
+			}
+			if arrow != "" || !isArrow(line[i:i+end]) {
+				return nil
+			}
+			arrow = line[i : i+end]
+			arrowAt = len(quoted)
+			i += end
+		}
+	}
+	if arrow == "" {
+		return nil
+	}
+	before, after := quoted[:arrowAt], quoted[arrowAt:]
+	if len(before) < 1 || len(before) > 2 || len(after) < 1 || len(after) > 2 {
+		return nil
+	}
+	e := &Edge{From: before[0], Arrow: arrow, To: after[len(after)-1], Label: label}
+	if len(before) == 2 {
+		e.FromLabel = before[1]
+	}
+	if len(after) == 2 {
+		e.ToLabel = after[0]
+	}
+	return e
+}
+
+// isArrow reports whether s is a PlantUML relationship arrow, optionally with an inline style like -[#red]-
+func isArrow(s string) bool {
+	if start := strings.Index(s, "["); start >= 0 {
+		end := strings.Index(s, "]")
+		if end < start {
+			return false
+		}
+		s = s[:start] + s[end+1:]
+	}
+	s = strings.TrimLeft(s, "<|*o#+")
+	s = strings.TrimRight(s, ">|*o#+")
+	return len(s) >= 1 && strings.Trim(s, "-") == "" || len(s) >= 2 && strings.Trim(s, ".") == ""
+}
+
+// String renders the relationship the way goplantuml does
+func (e *Edge) String() string {
+	parts := []string{quote(e.From)}
+	if e.FromLabel != "" {
+		parts = append(parts, quote(e.FromLabel))
+	}
+	parts = append(parts, e.Arrow)
+	if e.ToLabel != "" {
+		parts = append(parts, quote(e.ToLabel))
+	}
+	parts = append(parts, quote(e.To))
+	if e.Label != "" {
+		parts = append(parts, ":", e.Label)
+	}
+	return strings.Join(parts, " ")
+}
+
+// String renders the declaration line of the class, without the body
+func (c *Class) String() string {
+	parts := []string{c.Kind, quote(c.Name)}
+	if c.Alias != "" {
+		parts = append(parts, "as", c.Alias)
+	}
+	for _, s := range c.Stereotypes {
+		parts = append(parts, "<<"+s+">>")
+	}
+	if c.Extra != "" {
+		parts = append(parts, c.Extra)
+	}
+	parts = append(parts, "{")
+	return strings.Join(parts, " ")
+}
+
+// quote wraps a name in double quotes
+func quote(s string) string {
+	return `"` + s + `"`
+}
+
+// Render returns the PlantUML of the diagram
+func (d *Diagram) Render() string {
+	lines := []string{"@startuml"}
+	lines = append(lines, d.Header...)
+	for _, ns := range d.Namespaces {
+		lines = ns.render(lines, 0)
+	}
+	for _, c := range d.Classes {
+		lines = c.render(lines, 0)
+	}
+	if len(d.Edges) > 0 {
+		lines = append(lines, "")
+	}
+	for _, e := range d.Edges {
+		lines = append(lines, e.String())
+	}
+	for _, n := range d.Notes {
+		lines = append(lines, "", fmt.Sprintf("note %s of %s", n.Position, n.Target))
+		lines = append(lines, strings.Split(n.Text, "\n")...)
+		lines = append(lines, "end note")
+	}
+	if len(d.Footer) > 0 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, d.Footer...)
+	lines = append(lines, "@enduml")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (ns *Namespace) render(lines []string, depth int) []string {
+	keyword := "namespace"
+	if ns.Group {
+		keyword = "package"
+	}
+	lines = append(lines, strings.Repeat(indent, depth)+fmt.Sprintf("%s %s {", keyword, ns.Name))
+	for _, c := range ns.Classes {
+		lines = c.render(lines, depth+1)
+	}
+	for _, child := range ns.Children {
+		lines = child.render(lines, depth+1)
+	}
+	return append(lines, strings.Repeat(indent, depth)+"}")
+}
+
+func (c *Class) render(lines []string, depth int) []string {
+	lines = append(lines, strings.Repeat(indent, depth)+c.String())
+	for _, m := range c.Members {
+		if m == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, strings.Repeat(indent, depth+1)+m)
+	}
+	return append(lines, strings.Repeat(indent, depth)+"}")
+}
+
+// AllClasses returns the classes of all namespaces, depth first, followed by the classes outside of namespaces
+func (d *Diagram) AllClasses() []*Class {
+	result := []*Class{}
+	var walk func(ns *Namespace)
+	walk = func(ns *Namespace) {
+		result = append(result, ns.Classes...)
+		for _, child := range ns.Children {
+			walk(child)
+		}
+	}
+	for _, ns := range d.Namespaces {
+		walk(ns)
+	}
+	return append(result, d.Classes...)
+}
+
+// FindClass returns the class with the given full name or nil
+func (d *Diagram) FindClass(fullName string) *Class {
+	for _, c := range d.AllClasses() {
+		if c.FullName() == fullName {
+			return c
+		}
+	}
+	return nil
+}
+
+// AddClass adds a class to the namespace with the path of its Namespace, which is created at the top level if the
+// diagram has none yet, or outside of all namespaces if its Namespace is empty. Group tells whether a created
+// namespace is a group.
+func (d *Diagram) AddClass(c *Class, group bool) {
+	if c.Namespace == "" {
+		d.Classes = append(d.Classes, c)
+		return
+	}
+	if ns := findNamespace(d.Namespaces, c.Namespace); ns != nil {
+		ns.Classes = append(ns.Classes, c)
+		return
+	}
+	d.Namespaces = append(d.Namespaces, &Namespace{
+		Name:    c.Namespace,
+		Path:    c.Namespace,
+		Group:   group,
+		Classes: []*Class{c},
+	})
+}
+
+// findNamespace returns the namespace with the given path among the namespaces and their children, or nil
+func findNamespace(namespaces []*Namespace, path string) *Namespace {
+	for _, ns := range namespaces {
+		if ns.Path == path {
+			return ns
+		}
+		if child := findNamespace(ns.Children, path); child != nil {
+			return child
+		}
+	}
+	return nil
+}
+
+// RemoveEdges removes every relationship for which drop returns true
+func (d *Diagram) RemoveEdges(drop func(e *Edge) bool) {
+	kept := d.Edges[:0]
+	for _, e := range d.Edges {
+		if !drop(e) {
+			kept = append(kept, e)
+		}
+	}
+	d.Edges = kept
+}
+
+// AddEdge appends a relationship unless the same one is already part of the diagram
+func (d *Diagram) AddEdge(edge *Edge) {
+	for _, e := range d.Edges {
+		if *e == *edge {
+			return
+		}
+	}
+	d.Edges = append(d.Edges, edge)
+}
+
+// RemoveClass removes a class together with its relationships and notes. Namespaces left without classes are
+// removed as well.
+func (d *Diagram) RemoveClass(c *Class) {
+	d.detach(c)
+	refersTo := func(name string) bool {
+		return name == c.FullName() || name == c.Ref()
+	}
+	d.RemoveEdges(func(e *Edge) bool { return refersTo(e.From) || refersTo(e.To) })
+	notes := []*Note{}
+	for _, n := range d.Notes {
+		target, _, _ := strings.Cut(n.Target, "::")
+		if !refersTo(target) {
+			notes = append(notes, n)
+		}
+	}
+	d.Notes = notes
+}
+
+// detach takes a class out of its namespace, removing namespaces left without classes
+func (d *Diagram) detach(c *Class) {
+	without := func(classes []*Class) []*Class {
+		kept := []*Class{}
+		for _, other := range classes {
+			if other != c {
+				kept = append(kept, other)
+			}
+		}
+		return kept
+	}
+	var prune func(namespaces []*Namespace) []*Namespace
+	prune = func(namespaces []*Namespace) []*Namespace {
+		kept := []*Namespace{}
+		for _, ns := range namespaces {
+			ns.Classes = without(ns.Classes)
+			ns.Children = prune(ns.Children)
+			if len(ns.Classes) > 0 || len(ns.Children) > 0 {
+				kept = append(kept, ns)
+			}
+		}
+		return kept
+	}
+	d.Namespaces = prune(d.Namespaces)
+	d.Classes = without(d.Classes)
+}
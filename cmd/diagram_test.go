@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const diagramInput = `@startuml
+title Example
+legend
+<u><b>Legend</b></u>
+Render Fields: true
+end legend
+namespace example {
+    class "DatabaseUserService" << (S,Aquamarine) >> {
+        + DB any
+
+        + GetUser(id int) (*User, error)
+
+    }
+    interface "UserService"  {
+        + GetUser(id int) (*User, error)
+
+    }
+    namespace sub {
+        class "Builder" as Builder_generic_T <<[T]>> {
+        }
+        class "T" <<type parameter>> {
+            constraints: any
+        }
+    }
+}
+
+"example.UserService" <|-- "implements""example.DatabaseUserService"
+"example.DatabaseUserService""uses" o-- "example.User"
+"T" <-- "param" "Builder_generic_T"
+
+hide fields
+@enduml
+`
+
+func TestParseDiagram(t *testing.T) {
+	d := ParseDiagram(diagramInput)
+
+	expectedHeader := []string{"title Example", "legend", "<u><b>Legend</b></u>", "Render Fields: true", "end legend"}
+	if strings.Join(d.Header, "\n") != strings.Join(expectedHeader, "\n") {
+		t.Errorf("Header = %q, want %q", d.Header, expectedHeader)
+	}
+	if len(d.Footer) != 1 || d.Footer[0] != "hide fields" {
+		t.Errorf("Footer = %q, want [hide fields]", d.Footer)
+	}
+
+	classes := d.AllClasses()
+	expectedNames := []string{"example.DatabaseUserService", "example.UserService", "example.sub.Builder", "example.sub.T"}
+	if len(classes) != len(expectedNames) {
+		t.Fatalf("AllClasses() returned %d classes, want %d", len(classes), len(expectedNames))
+	}
+	for i, name := range expectedNames {
+		if classes[i].FullName() != name {
+			t.Errorf("AllClasses()[%d].FullName() = %v, want %v", i, classes[i].FullName(), name)
+		}
+	}
+
+	service := d.FindClass("example.DatabaseUserService")
+	if service == nil {
+		t.Fatal("FindClass() did not find example.DatabaseUserService")
+	}
+	if service.Kind != "class" || len(service.Stereotypes) != 1 || service.Stereotypes[0] != " (S,Aquamarine) " {
+		t.Errorf("unexpected declaration %+v", service)
+	}
+	if strings.Join(service.Members, "|") != "+ DB any||+ GetUser(id int) (*User, error)|" {
+		t.Errorf("Members = %q", service.Members)
+	}
+
+	builder := d.FindClass("example.sub.Builder")
+	if builder.Alias != "Builder_generic_T" || builder.Stereotypes[0] != "[T]" {
+		t.Errorf("unexpected generic declaration %+v", builder)
+	}
+
+	if len(d.Edges) != 3 {
+		t.Fatalf("Edges = %d, want 3", len(d.Edges))
+	}
+	expectedEdges := []Edge{
+		{From: "example.UserService", Arrow: "<|--", ToLabel: "implements", To: "example.DatabaseUserService"},
+		{From: "example.DatabaseUserService", FromLabel: "uses", Arrow: "o--", To: "example.User"},
+		{From: "T", Arrow: "<--", ToLabel: "param", To: "Builder_generic_T"},
+	}
+	for i, expected := range expectedEdges {
+		if *d.Edges[i] != expected {
+			t.Errorf("Edges[%d] = %+v, want %+v", i, *d.Edges[i], expected)
+		}
+	}
+}
+
+func TestDiagramRenderRoundTrip(t *testing.T) {
+	rendered := ParseDiagram(diagramInput).Render()
+	expected := `@startuml
+title Example
+legend
+<u><b>Legend</b></u>
+Render Fields: true
+end legend
+namespace example {
+    class "DatabaseUserService" << (S,Aquamarine) >> {
+        + DB any
+
+        + GetUser(id int) (*User, error)
+
+    }
+    interface "UserService" {
+        + GetUser(id int) (*User, error)
+
+    }
+    namespace sub {
+        class "Builder" as Builder_generic_T <<[T]>> {
+        }
+        class "T" <<type parameter>> {
+            constraints: any
+        }
+    }
+}
+
+"example.UserService" <|-- "implements" "example.DatabaseUserService"
+"example.DatabaseUserService" "uses" o-- "example.User"
+"T" <-- "param" "Builder_generic_T"
+
+hide fields
+@enduml
+`
+	if rendered != expected {
+		t.Errorf("Render() mismatch:\nExpected:\n%s\nActual:\n%s", expected, rendered)
+	}
+	if again := ParseDiagram(rendered).Render(); again != rendered {
+		t.Errorf("Render() is not stable:\n%s", again)
+	}
+}
+
+func TestParseEdge(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *Edge
+	}{
+		{
+			name:     "composition",
+			input:    `"example.Base" *-- "example.Derived"`,
+			expected: &Edge{From: "example.Base", Arrow: "*--", To: "example.Derived"},
+		},
+		{
+			name:     "alias with label",
+			input:    `"a.b" #.. "alias of""a.c"`,
+			expected: &Edge{From: "a.b", Arrow: "#..", ToLabel: "alias of", To: "a.c"},
+		},
+		{
+			name:  "multiplicities and label",
+			input: `"a.A" "1" o-- "*" "a.B" : items`,
+			expected: &Edge{
+				From: "a.A", FromLabel: "1", Arrow: "o--", ToLabel: "*", To: "a.B", Label: "items",
+			},
+		},
+		{
+			name:     "dependency with inline style",
+			input:    `"a.A" .[#red].> "a.B"`,
+			expected: &Edge{From: "a.A", Arrow: ".[#red].>", To: "a.B"},
+		},
+		{
+			name:     "not a relationship",
+			input:    `Render Fields: true`,
+			expected: nil,
+		},
+		{
+			name:     "quoted text without arrow",
+			input:    `"a" "b"`,
+			expected: nil,
+		},
+		{
+			name:     "hide directive",
+			input:    `hide fields`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseEdge(tt.input)
+			if tt.expected == nil {
+				if result != nil {
+					t.Errorf("ParseEdge() = %+v, want nil", result)
+				}
+				return
+			}
+			if result == nil || *result != *tt.expected {
+				t.Errorf("ParseEdge() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiagramEdges(t *testing.T) {
+	d := ParseDiagram(diagramInput)
+	d.AddEdge(&Edge{From: "T", Arrow: "<--", ToLabel: "param", To: "Builder_generic_T"})
+	if len(d.Edges) != 3 {
+		t.Errorf("AddEdge() added a duplicate edge")
+	}
+	d.RemoveEdges(func(e *Edge) bool { return e.Arrow == "o--" })
+	for _, e := range d.Edges {
+		if e.Arrow == "o--" {
+			t.Errorf("RemoveEdges() kept %v", e)
+		}
+	}
+	if len(d.Edges) != 2 {
+		t.Errorf("RemoveEdges() left %d edges, want 2", len(d.Edges))
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// Colors of go2uml diff
+const (
+	addedColor   = "#PaleGreen"
+	removedColor = "#LightCoral"
+	changedColor = "#Khaki"
+)
+
+// diffMarkup matches the background go2uml diff puts on the text of added, removed and changed members
+var diffMarkup = regexp.MustCompile(`</?back(:[^<>]*)?>`)
+
+// markMember returns a member line with its text on a background of the color, keeping the visibility in front
+func markMember(member, color string) string {
+	text := strings.TrimLeft(member, "+-#~ ")
+	return member[:len(member)-len(text)] + "<back:" + color + ">" + text + "</back>"
+}
+
+// plainMember returns a member line without visibility and markup for the change summary
+func plainMember(member string) string {
+	return strings.TrimSpace(markup.ReplaceAllString(strings.TrimLeft(member, "+-#~ "), ""))
+}
+
+// diffMembers merges the members of the old declaration of a class into the new one. Added members are marked green
+// and changed ones yellow, removed members are kept after the member they followed and marked red. It returns the
+// summary lines of the changes.
+func diffMembers(old, c *Class) []string {
+	oldLines := map[string]string{}
+	for _, member := range old.Members {
+		if isMember(member) {
+			oldLines[memberName(member)] = member
+		}
+	}
+	newLines := map[string]bool{}
+	for _, member := range c.Members {
+		if isMember(member) {
+			newLines[memberName(member)] = true
+		}
+	}
+	// removed members by the name of the member they followed, "" for the ones at the start
+	removed := map[string][]string{}
+	previous := ""
+	for _, member := range old.Members {
+		if !isMember(member) {
+			continue
+		}
+		if name := memberName(member); newLines[name] {
+			previous = name
+		} else {
+			removed[previous] = append(removed[previous], member)
+		}
+	}
+
+	summary := []string{}
+	if old.Kind != c.Kind {
+		summary = append(summary, fmt.Sprintf("    ~ %s -> %s", old.Kind, c.Kind))
+	}
+	members := []string{}
+	markRemoved := func(after string) {
+		for _, member := range removed[after] {
+			members = append(members, markMember(member, removedColor))
+			summary = append(summary, "    - "+plainMember(member))
+		}
+	}
+	markRemoved("")
+	for _, member := range c.Members {
+		if !isMember(member) {
+			members = append(members, member)
+			continue
+		}
+		name := memberName(member)
+		switch oldLine, ok := oldLines[name]; {
+		case !ok:
+			members = append(members, markMember(member, addedColor))
+			summary = append(summary, "    + "+plainMember(member))
+		case oldLine != member:
+			members = append(members, markMember(member, changedColor))
+			summary = append(summary, fmt.Sprintf("    ~ %s -> %s", plainMember(oldLine), plainMember(member)))
+		default:
+			members = append(members, member)
+		}
+		markRemoved(name)
+	}
+	c.Members = members
+	return summary
+}
+
+// diffDiagrams merges the old diagram into the new one: added classes and relationships are drawn green, removed ones
+// red and classes with changed members yellow. It returns the summary of the changes, one line per class or
+// relationship followed by indented lines for the members.
+func diffDiagrams(old, d *Diagram) []string {
+	summary := []string{}
+	oldClasses := map[string]*Class{}
+	for _, c := range old.AllClasses() {
+		oldClasses[c.FullName()] = c
+	}
+	newClasses := map[string]bool{}
+	for _, c := range d.AllClasses() {
+		newClasses[c.FullName()] = true
+		previous, ok := oldClasses[c.FullName()]
+		if !ok {
+			addColor(c, addedColor)
+			summary = append(summary, "+ "+c.Kind+" "+c.FullName())
+			continue
+		}
+		if changes := diffMembers(previous, c); len(changes) > 0 {
+			addColor(c, changedColor)
+			summary = append(summary, "~ "+c.Kind+" "+c.FullName())
+			summary = append(summary, changes...)
+		}
+	}
+	for _, c := range old.AllClasses() {
+		if !newClasses[c.FullName()] {
+			addColor(c, removedColor)
+			d.AddClass(c, false)
+			summary = append(summary, "- "+c.Kind+" "+c.FullName())
+		}
+	}
+
+	relationship := func(e *Edge) string {
+		return fmt.Sprintf("relationship %s %s %s", e.From, e.Arrow, e.To)
+	}
+	oldEdges := map[string]bool{}
+	for _, e := range old.Edges {
+		oldEdges[e.String()] = true
+	}
+	newEdges := map[string]bool{}
+	for _, e := range d.Edges {
+		newEdges[e.String()] = true
+		if !oldEdges[e.String()] {
+			summary = append(summary, "+ "+relationship(e))
+			e.Arrow = colorArrow(e.Arrow, addedColor)
+		}
+	}
+	for _, e := range old.Edges {
+		if !newEdges[e.String()] {
+			summary = append(summary, "- "+relationship(e))
+			e.Arrow = colorArrow(e.Arrow, removedColor)
+			d.Edges = append(d.Edges, e)
+		}
+	}
+	return summary
+}
+
+// loadDiagram renders the diagram of the Go packages in a directory and its subdirectories, with aggregations so that
+// new and removed fields of other types show up as relationships
+func loadDiagram(dir string) (*Diagram, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{dir}
+	sources, err := collectSources(dirs, true, nil, &sourceFilter{build: buildContext("", "", "")})
+	if err != nil {
+		return nil, err
+	}
+	staged, err := stageDirectories(dirs, nil, sources)
+	if err != nil {
+		return nil, err
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(staged.dirs, staged.ignored, true, 0)
+	staged.remove()
+	if err != nil {
+		return nil, err
+	}
+	_ = result.SetRenderingOptions(map[goplantuml.RenderingOption]any{
+		goplantuml.RenderFields:         true,
+		goplantuml.RenderMethods:        true,
+		goplantuml.RenderPrivateMembers: true,
+		goplantuml.RenderAggregations:   true,
+	})
+	d := ParseDiagram(result.Render())
+	repairRelationships(d, sources.dirs, dirs)
+	return d, nil
+}
+
+// loadSide renders one side of go2uml diff, which is a directory or a revision of the git repository of the current
+// directory. Revisions are checked out into a temporary worktree, of which the directory matching the current one is
+// rendered.
+func loadSide(side string) (*Diagram, error) {
+	if info, err := os.Stat(side); err == nil && info.IsDir() {
+		return loadDiagram(side)
+	}
+	if !isRevision(".", side) {
+		return nil, fmt.Errorf("%s is neither a directory nor a git revision", side)
+	}
+	w, err := checkoutRevision(".", side)
+	if err != nil {
+		return nil, err
+	}
+	defer w.remove()
+	return loadDiagram(w.dir)
+}
+
+// isDiffCommand reports whether the command line arguments, without the program name, run go2uml diff
+func isDiffCommand(args []string) bool {
+	return len(args) > 0 && args[0] == "diff"
+}
+
+// runDiff implements go2uml diff <old> <new>. It writes the merged diagram to the output and the change summary to
+// stderr.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "plantuml", "output format: plantuml or mermaid")
+	output := flags.String("output", "", "output file path, if omitted, the diagram is written to stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: go2uml diff [-format=plantuml|mermaid] [-output=FILE] <old> <new>")
+		fmt.Fprintln(stderr, "<old> and <new> are directories or git revisions")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	old, err := loadSide(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	d, err := loadSide(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	summary := diffDiagrams(old, d)
+	if len(summary) == 0 {
+		fmt.Fprintln(stderr, "no changes")
+	}
+	for _, line := range summary {
+		fmt.Fprintln(stderr, line)
+	}
+
+	rendered, err := convertDiagram(d.Render(), strings.ToLower(*format))
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	if *output == "" {
+		fmt.Fprint(stdout, rendered)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(rendered), 0o600); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	return 0
+}
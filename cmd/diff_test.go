@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	// oldShop is the shop package before the change
+	oldShop = `package shop
+
+type Order struct {
+	ID    int
+	Price float64
+	Note  string
+}
+
+func (o *Order) Total() float64 { return o.Price }
+
+type Legacy struct{}
+`
+	// newShop changes a field and a method of Order, replaces a field with one of the new type Customer and removes
+	// Legacy
+	newShop = `package shop
+
+type Order struct {
+	ID       int
+	Price    int64
+	Customer *Customer
+}
+
+func (o *Order) Total() int64 { return int64(o.Price) }
+
+type Customer struct {
+	Name string
+}
+`
+)
+
+// shopSummary is the change summary from oldShop to newShop
+var shopSummary = []string{
+	"+ class shop.Customer",
+	"~ class shop.Order",
+	"    ~ Price float64 -> Price int64",
+	"    - Note string",
+	"    + Customer *Customer",
+	"    ~ Total() float64 -> Total() int64",
+	"- class shop.Legacy",
+	"+ relationship shop.Order o-- shop.Customer",
+}
+
+func TestDiffDiagrams(t *testing.T) {
+	old := ParseDiagram(`@startuml
+namespace shop {
+    class "Order" << (S,Aquamarine) >> {
+        + ID int
+        + Note string
+        + Price float64
+
+    }
+    class "Legacy" << (S,Aquamarine) >> {
+    }
+}
+"shop.Order" o-- "shop.Legacy"
+@enduml
+`)
+	d := ParseDiagram(`@startuml
+namespace shop {
+    class "Order" << (S,Aquamarine) >> {
+        + ID int
+        + Price int64
+        + Customer *Customer
+
+    }
+    interface "Customer" {
+    }
+}
+"shop.Order" o-- "shop.Customer"
+@enduml
+`)
+	summary := diffDiagrams(old, d)
+	expected := []string{
+		"~ class shop.Order",
+		"    - Note string",
+		"    ~ Price float64 -> Price int64",
+		"    + Customer *Customer",
+		"+ interface shop.Customer",
+		"- class shop.Legacy",
+		"+ relationship shop.Order o-- shop.Customer",
+		"- relationship shop.Order o-- shop.Legacy",
+	}
+	if !equalStrings(summary, expected) {
+		t.Errorf("diffDiagrams() = %q, want %q", summary, expected)
+	}
+
+	order := d.FindClass("shop.Order")
+	members := []string{
+		"+ ID int",
+		"+ <back:#LightCoral>Note string</back>",
+		"+ <back:#Khaki>Price int64</back>",
+		"+ <back:#PaleGreen>Customer *Customer</back>",
+		"",
+	}
+	if !equalStrings(order.Members, members) {
+		t.Errorf("Members = %q, want %q", order.Members, members)
+	}
+	extras := map[string]string{"shop.Order": changedColor, "shop.Customer": addedColor, "shop.Legacy": removedColor}
+	for name, want := range extras {
+		if c := d.FindClass(name); c == nil || c.Extra != want {
+			t.Errorf("Extra of %s = %+v, want %q", name, c, want)
+		}
+	}
+	edges := []string{
+		`"shop.Order" o-[#PaleGreen]- "shop.Customer"`,
+		`"shop.Order" o-[#LightCoral]- "shop.Legacy"`,
+	}
+	for i, want := range edges {
+		if line := d.Edges[i].String(); line != want {
+			t.Errorf("edge %d = %s, want %s", i, line, want)
+		}
+	}
+
+	mermaid, err := ConvertToMermaid(d.Render())
+	if err != nil {
+		t.Fatalf("ConvertToMermaid() error = %v", err)
+	}
+	if !strings.Contains(mermaid, "        +Note string\n") || strings.Contains(mermaid, "back") {
+		t.Errorf("ConvertToMermaid() kept the member markup:\n%s", mermaid)
+	}
+}
+
+func TestIsDiffCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected bool
+		dirs     []string
+	}{
+		{name: "diff command", args: []string{"diff", "main", "."}, expected: true},
+		{name: "no arguments", args: []string{}, dirs: []string{}},
+		{name: "directory named diff as path", args: []string{"./diff"}, dirs: []string{"./diff"}},
+		{name: "directory named diff after --", args: []string{"--", "diff"}, dirs: []string{"diff"}},
+		{name: "directory named diff after flags", args: []string{"-recursive", "diff"}, dirs: []string{"diff"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isDiffCommand(tt.args); result != tt.expected {
+				t.Errorf("isDiffCommand(%q) = %v, want %v", tt.args, result, tt.expected)
+			}
+			if tt.expected {
+				return
+			}
+			flags := flag.NewFlagSet("go2uml", flag.ContinueOnError)
+			flags.Bool("recursive", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !equalStrings(flags.Args(), tt.dirs) {
+				t.Errorf("Args() = %q, want %q", flags.Args(), tt.dirs)
+			}
+		})
+	}
+}
+
+func TestRunDiffDirectories(t *testing.T) {
+	root := writeFiles(t, map[string]string{"old/shop/shop.go": oldShop, "new/shop/shop.go": newShop})
+	output := filepath.Join(root, "diff.puml")
+	var stdout, stderr bytes.Buffer
+	args := []string{"-output=" + output, filepath.Join(root, "old"), filepath.Join(root, "new")}
+	if code := runDiff(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("runDiff() = %d, stderr %s", code, stderr.String())
+	}
+	if summary := strings.Split(strings.TrimSpace(stderr.String()), "\n"); !equalStrings(summary, shopSummary) {
+		t.Errorf("summary = %q, want %q", summary, shopSummary)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", output, err)
+	}
+	if !strings.Contains(string(data), `class "Legacy" << (S,Aquamarine) >> #LightCoral {`) {
+		t.Errorf("diagram does not show the removed class:\n%s", data)
+	}
+
+	if code := runDiff([]string{filepath.Join(root, "old")}, &stdout, &stderr); code != 2 {
+		t.Errorf("runDiff() with one side = %d, want 2", code)
+	}
+	if code := runDiff([]string{filepath.Join(root, "old"), "missing"}, &stdout, &stderr); code != 1 {
+		t.Errorf("runDiff() with an unknown side = %d, want 1", code)
+	}
+}
+
+func TestRunDiffRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := writeFiles(t, map[string]string{"go.mod": "module example.com/shop\n", "shop/shop.go": oldShop})
+	git := func(args ...string) {
+		t.Helper()
+		if _, err := runGit(root, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	git("add", "-A")
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "old")
+	if err := os.WriteFile(filepath.Join(root, "shop", "shop.go"), []byte(newShop), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+
+	var stdout, stderr bytes.Buffer
+	if code := runDiff([]string{"HEAD", "."}, &stdout, &stderr); code != 0 {
+		t.Fatalf("runDiff() = %d, stderr %s", code, stderr.String())
+	}
+	if summary := strings.Split(strings.TrimSpace(stderr.String()), "\n"); !equalStrings(summary, shopSummary) {
+		t.Errorf("summary = %q, want %q", summary, shopSummary)
+	}
+	if worktrees, _ := runGit(root, "worktree", "list"); strings.Count(worktrees, "\n") != 1 {
+		t.Errorf("the temporary worktree was not removed:\n%s", worktrees)
+	}
+
+	stderr.Reset()
+	if code := runDiff([]string{"HEAD", "HEAD"}, &stdout, &stderr); code != 0 || stderr.String() != "no changes\n" {
+		t.Errorf("runDiff() = %d, stderr %q, want no changes", code, stderr.String())
+	}
+}
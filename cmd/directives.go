@@ -0,0 +1,215 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// directivePrefix starts the comment lines that control how a type or field is drawn, e.g. //go2uml:hide.
+// go/ast treats such lines as directives, so they are not part of doc comments shown with -show-docs.
+const directivePrefix = "//go2uml:"
+
+const (
+	directiveHide       = "hide"
+	directiveStereotype = "stereotype"
+	directiveNote       = "note"
+	directiveColor      = "color"
+	directiveGroup      = "group"
+)
+
+// groupName matches the names of groups, which become PlantUML packages and Mermaid namespaces
+var groupName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// directive is a //go2uml: comment line, e.g. //go2uml:stereotype=Repository or //go2uml:note "Cached for 5m"
+type directive struct {
+	name  string
+	value string
+	pos   token.Pos
+}
+
+// parseDirectives returns the directives of the comment groups. Values are separated from the name by "=" or a
+// space and may be quoted.
+func parseDirectives(groups ...*ast.CommentGroup) []directive {
+	result := []directive{}
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			text, ok := strings.CutPrefix(comment.Text, directivePrefix)
+			if !ok {
+				continue
+			}
+			name, value := strings.TrimSpace(text), ""
+			if i := strings.IndexAny(name, "= "); i >= 0 {
+				name, value = name[:i], strings.TrimSpace(name[i+1:])
+			}
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			result = append(result, directive{name: name, value: value, pos: comment.Pos()})
+		}
+	}
+	return result
+}
+
+// memberComments returns the doc and line comments of the fields and methods of a type, by member name
+func memberComments(t *sourceType) map[string][]*ast.CommentGroup {
+	result := map[string][]*ast.CommentGroup{}
+	var fields []*ast.Field
+	switch spec := t.spec.Type.(type) {
+	case *ast.StructType:
+		fields = spec.Fields.List
+	case *ast.InterfaceType:
+		fields = spec.Methods.List
+	}
+	for _, field := range fields {
+		for _, name := range field.Names {
+			result[name.Name] = append(result[name.Name], field.Doc, field.Comment)
+		}
+	}
+	for _, m := range t.methods {
+		name := m.decl.Name.Name
+		result[name] = append(result[name], m.decl.Doc)
+	}
+	return result
+}
+
+// memberDirectives are the directives of the fields and methods of a type, by member name
+func memberDirectives(t *sourceType) map[string][]directive {
+	result := map[string][]directive{}
+	for name, groups := range memberComments(t) {
+		result[name] = parseDirectives(groups...)
+	}
+	return result
+}
+
+// applyDirectives changes the diagram as the //go2uml: directives of the sources ask for. Types and members can
+// be hidden, get a stereotype or a note, and types can be colored and moved into a group.
+func applyDirectives(d *Diagram, idx *sourceIndex) {
+	warn := func(message string, dir directive) {
+		slog.Warn(message, "directive", directivePrefix[2:]+dir.name, "position", idx.fset.Position(dir.pos).String())
+	}
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		applyMemberDirectives(d, c, memberDirectives(t), warn)
+		group := ""
+		hidden := false
+		for _, dir := range parseDirectives(t.doc, t.spec.Comment) {
+			switch dir.name {
+			case directiveHide:
+				hidden = true
+			case directiveStereotype:
+				c.Stereotypes = append(c.Stereotypes, dir.value)
+			case directiveNote:
+				d.Notes = append(d.Notes, &Note{Position: docNotePosition, Target: c.Ref(), Text: dir.value})
+			case directiveColor:
+				c.Extra = strings.TrimSpace(c.Extra + " #" + strings.TrimPrefix(dir.value, "#"))
+			case directiveGroup:
+				if !groupName.MatchString(dir.value) {
+					warn("group names must be identifiers, the directive is ignored", dir)
+					continue
+				}
+				group = dir.value
+			default:
+				warn("unknown directive is ignored", dir)
+			}
+		}
+		switch {
+		case hidden:
+			d.RemoveClass(c)
+		case group != "":
+			d.moveToGroup(c, group)
+		}
+	}
+}
+
+// applyMemberDirectives applies the directives of the members of a class
+func applyMemberDirectives(d *Diagram, c *Class, directives map[string][]directive, warn func(string, directive)) {
+	hidden := map[string]bool{}
+	for i, m := range c.Members {
+		name := memberName(m)
+		for _, dir := range directives[name] {
+			switch dir.name {
+			case directiveHide:
+				hidden[name] = true
+			case directiveStereotype:
+				c.Members[i] += " <<" + dir.value + ">>"
+			case directiveNote:
+				d.Notes = append(
+					d.Notes,
+					&Note{Position: docNotePosition, Target: c.Ref() + "::" + name, Text: dir.value},
+				)
+			case directiveColor, directiveGroup:
+				warn("directive only applies to types and is ignored", dir)
+			default:
+				warn("unknown directive is ignored", dir)
+			}
+		}
+	}
+	removeMembers(d, c, hidden)
+}
+
+// removeMembers removes the members with the given names from a class together with the aggregations of removed
+// fields. Blank lines left next to each other are merged.
+func removeMembers(d *Diagram, c *Class, names map[string]bool) {
+	if len(names) == 0 {
+		return
+	}
+	members := []string{}
+	for _, m := range c.Members {
+		if names[memberName(m)] {
+			continue
+		}
+		if m == "" && len(members) > 0 && members[len(members)-1] == "" {
+			continue
+		}
+		members = append(members, m)
+	}
+	c.Members = members
+	d.RemoveEdges(func(e *Edge) bool {
+		return e.From == c.FullName() && e.Arrow == aggregationArrow && names[e.Label]
+	})
+}
+
+// moveToGroup moves a class into the top level group with the given name and renames its relationships and notes
+func (d *Diagram) moveToGroup(c *Class, group string) {
+	oldName := c.FullName()
+	d.detach(c)
+	found := false
+	for _, ns := range d.Namespaces {
+		found = found || ns.Path == group
+	}
+	if !found {
+		d.Namespaces = append(d.Namespaces, &Namespace{Name: group, Path: group, Group: true})
+	}
+	c.Package = c.SourceNamespace()
+	c.Namespace = group
+	d.AddClass(c, false)
+	if c.Alias != "" {
+		return
+	}
+	for _, e := range d.Edges {
+		if e.From == oldName {
+			e.From = c.FullName()
+		}
+		if e.To == oldName {
+			e.To = c.FullName()
+		}
+	}
+	for _, n := range d.Notes {
+		if target, member, ok := strings.Cut(n.Target, "::"); target == oldName {
+			n.Target = c.FullName()
+			if ok {
+				n.Target += "::" + member
+			}
+		}
+	}
+}
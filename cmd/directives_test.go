@@ -0,0 +1,239 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		comment  string
+		expected []directive
+	}{
+		{
+			name:     "switch",
+			comment:  "//go2uml:hide",
+			expected: []directive{{name: "hide"}},
+		},
+		{
+			name:     "value after equals sign",
+			comment:  "//go2uml:stereotype=Repository",
+			expected: []directive{{name: "stereotype", value: "Repository"}},
+		},
+		{
+			name:     "quoted value after space",
+			comment:  `//go2uml:note "Cached for 5m, see a=b"`,
+			expected: []directive{{name: "note", value: "Cached for 5m, see a=b"}},
+		},
+		{
+			name:     "other comments are skipped",
+			comment:  "// Store keeps users.\n//go:generate mockgen\n// go2uml:hide\n//go2uml:color=#ffeeaa",
+			expected: []directive{{name: "color", value: "#ffeeaa"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "x.go", "package x\n\n"+tt.comment+"\ntype X int\n", parser.ParseComments)
+			if err != nil {
+				t.Fatalf("ParseFile() error = %v", err)
+			}
+			result := parseDirectives(file.Comments...)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parseDirectives() = %v, want %v", result, tt.expected)
+			}
+			for i, d := range result {
+				if d.name != tt.expected[i].name || d.value != tt.expected[i].value {
+					t.Errorf("parseDirectives()[%d] = %q=%q, want %q=%q", i, d.name, d.value, tt.expected[i].name, tt.expected[i].value)
+				}
+			}
+		})
+	}
+}
+
+// directedSource declares types with directives on the types and their members
+const directedSource = `package store
+
+// Store keeps users.
+//
+//go2uml:stereotype=Repository
+//go2uml:color=#ffeeaa
+type Store struct {
+	//go2uml:note "Guarded by mu"
+	Users []*User
+	cache *User //go2uml:hide
+	Name  string //go2uml:stereotype=Key
+}
+
+// Find returns a user.
+//
+//go2uml:hide
+func (s *Store) Find(id int) *User { return nil }
+
+//go2uml:group=Domain
+//go2uml:note "The user"
+type User struct{}
+
+//go2uml:hide
+type Internal struct {
+	User *User
+}
+`
+
+// directedDiagram renders the diagram of directedSource with the directives applied
+func directedDiagram(t *testing.T) *Diagram {
+	t.Helper()
+	root := writeFiles(t, map[string]string{"store/store.go": directedSource})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	if !sources.directives {
+		t.Fatalf("collectSources() found no directives")
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	_ = result.SetRenderingOptions(map[goplantuml.RenderingOption]any{
+		goplantuml.RenderAggregations:      true,
+		goplantuml.AggregatePrivateMembers: true,
+	})
+	d := ParseDiagram(result.Render())
+	idx := loadSourceIndex(sources, dirs)
+	addDocs(d, idx, false)
+	applyDirectives(d, idx)
+	return d
+}
+
+func TestApplyDirectives(t *testing.T) {
+	d := directedDiagram(t)
+
+	if d.FindClass("store.Internal") != nil {
+		t.Errorf("hidden class store.Internal is still drawn")
+	}
+	if d.FindClass("store.User") != nil || d.FindClass("Domain.User") == nil {
+		t.Errorf("store.User was not moved into the group Domain")
+	}
+
+	store := d.FindClass("store.Store")
+	if !containsString(store.Stereotypes, "Repository") {
+		t.Errorf("Stereotypes = %q, want Repository", store.Stereotypes)
+	}
+	if !strings.HasSuffix(store.Extra, "#ffeeaa") {
+		t.Errorf("Extra = %q, want the color", store.Extra)
+	}
+	members := strings.Join(store.Members, "\n")
+	for _, hidden := range []string{"cache", "Find"} {
+		if strings.Contains(members, hidden) {
+			t.Errorf("Members = %q, want %s hidden", store.Members, hidden)
+		}
+	}
+	if !strings.Contains(members, "+ Name string <<Key>>") {
+		t.Errorf("Members = %q, want Name with the stereotype Key", store.Members)
+	}
+
+	edges := []string{}
+	for _, e := range d.Edges {
+		edges = append(edges, e.String())
+	}
+	expected := []string{`"store.Store" o-- "Domain.User"`}
+	if !equalStrings(edges, expected) {
+		t.Errorf("Edges = %q, want %q", edges, expected)
+	}
+
+	notes := map[string]string{}
+	for _, n := range d.Notes {
+		notes[n.Target] = n.Text
+	}
+	expectedNotes := map[string]string{
+		"store.Store":        "Store keeps users.",
+		"store.Store::Users": "Guarded by mu",
+		"Domain.User":        "The user",
+	}
+	if len(notes) != len(expectedNotes) {
+		t.Errorf("Notes = %q, want %q", notes, expectedNotes)
+	}
+	for target, text := range expectedNotes {
+		if notes[target] != text {
+			t.Errorf("note of %s = %q, want %q", target, notes[target], text)
+		}
+	}
+
+	rendered := d.Render()
+	if !strings.Contains(rendered, "package Domain {\n    class \"User\"") {
+		t.Errorf("Render() is missing the group:\n%s", rendered)
+	}
+	if parsed := ParseDiagram(rendered); len(parsed.Namespaces) != 2 || !parsed.Namespaces[1].Group {
+		t.Errorf("ParseDiagram() did not parse the group back:\n%s", rendered)
+	}
+}
+
+func TestApplyDirectivesGroupDeprecated(t *testing.T) {
+	source := `package store
+
+// User is a user.
+//
+// Deprecated: use model.User instead.
+//
+//go2uml:group=Domain
+type User struct {
+	// Deprecated: use Name.
+	Login string
+	Name  string
+}
+`
+	root := writeFiles(t, map[string]string{"store/store.go": source})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	idx := loadSourceIndex(sources, dirs)
+	applyDirectives(d, idx)
+	applyDeprecations(d, idx, false)
+
+	user := d.FindClass("Domain.User")
+	if user == nil {
+		t.Fatalf("store.User was not moved into the group Domain")
+	}
+	if !containsString(user.Stereotypes, deprecatedStereotype) || user.Extra != deprecatedColor {
+		t.Errorf("Domain.User = %q, want it marked as deprecated", user.String())
+	}
+	if !containsString(user.Members, "+ <s>Login string</s> <<deprecated>>") {
+		t.Errorf("Members = %q, want Login marked as deprecated", user.Members)
+	}
+	if idx.lookup(user) == nil {
+		t.Errorf("lookup(Domain.User) = nil, want the declaration of store.User")
+	}
+}
+
+func TestConvertToMermaidDirectives(t *testing.T) {
+	result, err := ConvertToMermaid(directedDiagram(t).Render())
+	if err != nil {
+		t.Fatalf("ConvertToMermaid() error = %v", err)
+	}
+	for _, expected := range []string{
+		"namespace Domain {\n    class User {\n        <<struct>>\n    }\n    }",
+		"class Store {\n        <<Repository>>",
+		"+Name string Key",
+		`note for Store "Users: Guarded by mu"`,
+		"style Store fill:#ffeeaa",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ConvertToMermaid() is missing %q:\n%s", expected, result)
+		}
+	}
+}
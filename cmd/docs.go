@@ -0,0 +1,44 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc"
+	"strings"
+)
+
+// docNotePosition is the side of its class a doc comment note is drawn on
+const docNotePosition = "right"
+
+// docText returns the text of a doc comment, or only its first sentence if summary is set
+func docText(comment *ast.CommentGroup, summary bool) string {
+	if comment == nil {
+		return ""
+	}
+	text := strings.TrimSpace(comment.Text())
+	if summary {
+		text = new(doc.Package).Synopsis(text)
+	}
+	return text
+}
+
+// tooltip returns a PlantUML link without a URL, which SVG output shows as a tooltip
+func tooltip(text string) string {
+	replacer := strings.NewReplacer("\n", " ", "{", "(", "}", ")", "[", "(", "]", ")")
+	return "[[{" + replacer.Replace(text) + "}]]"
+}
+
+// addDocs attaches the doc comment of every class declared in the sources as a note and as a tooltip
+func addDocs(d *Diagram, idx *sourceIndex, summary bool) {
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		text := docText(t.doc, summary)
+		if text == "" {
+			continue
+		}
+		d.Notes = append(d.Notes, &Note{Position: docNotePosition, Target: c.Ref(), Text: text})
+		c.Extra = strings.TrimSpace(tooltip(text) + " " + c.Extra)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// documentedSource declares types with the different places a doc comment can be written
+const documentedSource = `package store
+
+// Store keeps users. It is safe for concurrent use.
+//
+// The zero value is not usable, see NewStore.
+type Store struct{}
+
+type (
+	// Reader reads {users} from a [Store].
+	Reader interface {
+		Read() error
+	}
+
+	Undocumented struct{}
+)
+
+// Builder builds values of type T.
+type Builder[T any] struct{}
+
+func (b *Builder[T]) Build() T {
+	var zero T
+	return zero
+}
+`
+
+// documentedDiagram renders the diagram of documentedSource with the doc comments attached
+func documentedDiagram(t *testing.T, summary bool) *Diagram {
+	t.Helper()
+	root := writeFiles(t, map[string]string{"store/store.go": documentedSource})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	addDocs(d, loadSourceIndex(sources, dirs), summary)
+	return d
+}
+
+func TestAddDocs(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  bool
+		expected map[string]string
+	}{
+		{
+			name:    "full comments",
+			summary: false,
+			expected: map[string]string{
+				"store.Store":       "Store keeps users. It is safe for concurrent use.\n\nThe zero value is not usable, see NewStore.",
+				"store.Reader":      "Reader reads {users} from a [Store].",
+				"Builder_generic_T": "Builder builds values of type T.",
+			},
+		},
+		{
+			name:    "first sentence",
+			summary: true,
+			expected: map[string]string{
+				"store.Store":       "Store keeps users.",
+				"store.Reader":      "Reader reads {users} from a [Store].",
+				"Builder_generic_T": "Builder builds values of type T.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := documentedDiagram(t, tt.summary)
+			notes := map[string]string{}
+			for _, n := range d.Notes {
+				notes[n.Target] = n.Text
+			}
+			if len(notes) != len(tt.expected) {
+				t.Errorf("Notes = %v, want %v", notes, tt.expected)
+			}
+			for target, text := range tt.expected {
+				if notes[target] != text {
+					t.Errorf("note of %s = %q, want %q", target, notes[target], text)
+				}
+			}
+		})
+	}
+}
+
+func TestAddDocsTooltip(t *testing.T) {
+	d := documentedDiagram(t, true)
+
+	reader := d.FindClass("store.Reader")
+	if reader.Extra != "[[{Reader reads (users) from a (Store).}]]" {
+		t.Errorf("Extra = %q, want the tooltip", reader.Extra)
+	}
+	if undocumented := d.FindClass("store.Undocumented"); undocumented.Extra != "" {
+		t.Errorf("Extra = %q, want no tooltip", undocumented.Extra)
+	}
+
+	rendered := d.Render()
+	if !strings.Contains(rendered, "note right of store.Reader\nReader reads {users} from a [Store].\nend note") {
+		t.Errorf("Render() is missing the note:\n%s", rendered)
+	}
+}
+
+func TestAddDocsNestedPackage(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"internal/db/db.go": "package db\n\n// Conn is a database connection.\ntype Conn struct{}\n",
+	})
+	dirs := []string{root}
+	sources, err := collectSources(dirs, true, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, true, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	addDocs(d, loadSourceIndex(sources, dirs), false)
+
+	if len(d.Notes) != 1 || d.Notes[0].Target != "db.Conn" || d.Notes[0].Text != "Conn is a database connection." {
+		t.Errorf("Notes = %v, want the doc comment of db.Conn", d.Notes)
+	}
+}
+
+func TestConvertToMermaidNotes(t *testing.T) {
+	input := `@startuml
+namespace example {
+    interface "UserService" [[{Provides the class and interface of users}]] {
+        + GetUser(id int) (*User, error)
+    }
+    class "Builder" as Builder_generic_T <<[T]>> {
+    }
+}
+
+note right of example.UserService
+UserService provides "user" operations.
+It -- has two lines.
+end note
+
+note right of Builder_generic_T
+Builder builds.
+end note
+@enduml
+`
+	result, err := ConvertToMermaid(input)
+	if err != nil {
+		t.Fatalf("ConvertToMermaid() error = %v", err)
+	}
+	for _, expected := range []string{
+		"class UserService {\n        <<interface>>",
+		`note for UserService "UserService provides 'user' operations.<br>It -- has two lines."`,
+		`note for Builder "Builder builds."`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ConvertToMermaid() is missing %q:\n%s", expected, result)
+		}
+	}
+	if strings.Contains(result, "Provides the class") {
+		t.Errorf("ConvertToMermaid() kept the tooltip:\n%s", result)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+	"golang.org/x/tools/go/packages"
+)
+
+// externalStereotype marks the stub classes of interfaces declared outside of the diagram
+const externalStereotype = "external"
+
+// stdlibInterfaces returns the interfaces of the standard library drawn with -stdlib-interfaces
+func stdlibInterfaces() []string {
+	return []string{
+		"error",
+		"fmt.Stringer",
+		"io.Reader",
+		"io.Writer",
+		"io.Closer",
+		"io.ReaderFrom",
+		"io.WriterTo",
+		"sort.Interface",
+		"encoding.TextMarshaler",
+		"encoding.TextUnmarshaler",
+		"encoding/json.Marshaler",
+		"encoding/json.Unmarshaler",
+		"net/http.Handler",
+	}
+}
+
+// splitList splits a comma separated list, trims its entries and removes empty and duplicate ones
+func splitList(list string) []string {
+	result := []string{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !containsString(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// splitInterfaceName splits an interface like net/http.Handler into its import path and name.
+// It returns false for predeclared interfaces such as error.
+func splitInterfaceName(name string) (string, string, bool) {
+	dot := strings.LastIndex(name, ".")
+	if dot <= 0 || dot < strings.LastIndex(name, "/") {
+		return "", name, false
+	}
+	return name[:dot], name[dot+1:], true
+}
+
+// resolveInterfaces looks up the external interfaces in the packages of one load, including their dependencies
+func resolveInterfaces(loaded []*packages.Package, names []string) []*types.TypeName {
+	byPath := map[string]*packages.Package{}
+	packages.Visit(loaded, nil, func(p *packages.Package) {
+		byPath[p.PkgPath] = p
+	})
+	result := []*types.TypeName{}
+	for _, name := range names {
+		var obj types.Object
+		if pkgPath, typeName, ok := splitInterfaceName(name); ok {
+			if p := byPath[pkgPath]; p != nil && p.Types != nil {
+				obj = p.Types.Scope().Lookup(typeName)
+			}
+		} else {
+			obj = types.Universe.Lookup(name)
+		}
+		typeName, ok := obj.(*types.TypeName)
+		if !ok || !types.IsInterface(typeName.Type()) {
+			slog.Warn("external interface not found", "interface", name)
+			continue
+		}
+		result = append(result, typeName)
+	}
+	return result
+}
+
+// externalRelations returns the implementations of the external interfaces by classes of the diagram and the
+// interfaces that are implemented at least once, sorted by class name
+func (tp *typedPackages) externalRelations(d *Diagram, labels bool) ([]*Edge, []*types.TypeName) {
+	inDiagram := map[string]struct{}{}
+	for _, c := range d.AllClasses() {
+		inDiagram[c.FullName()] = struct{}{}
+	}
+	label := ""
+	if labels {
+		label = implementsLabel
+	}
+
+	edges := []*Edge{}
+	used := map[string]*types.TypeName{}
+	for _, obj := range tp.namedTypes() {
+		name := tp.className(obj)
+		if _, ok := inDiagram[name]; !ok || isGeneric(obj) {
+			continue
+		}
+		for _, iface := range tp.external[obj.Pkg()] {
+			ifaceName := tp.className(iface)
+			if implementsInterface(obj.Type(), iface.Type().Underlying().(*types.Interface)) {
+				edges = append(edges, &Edge{From: ifaceName, Arrow: implementsArrow, ToLabel: label, To: name})
+				used[ifaceName] = iface
+			}
+		}
+	}
+	names := []string{}
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	interfaces := []*types.TypeName{}
+	for _, name := range names {
+		interfaces = append(interfaces, used[name])
+	}
+	return edges, interfaces
+}
+
+// interfaceStub returns the class drawn for an interface declared outside of the diagram, with its methods
+func interfaceStub(iface *types.TypeName) *Class {
+	qualifier := func(p *types.Package) string { return p.Name() }
+	c := &Class{
+		Kind:        "interface",
+		Name:        iface.Name(),
+		Stereotypes: []string{externalStereotype},
+	}
+	if iface.Pkg() != nil {
+		c.Namespace = path.Base(iface.Pkg().Path())
+	}
+	methods := iface.Type().Underlying().(*types.Interface)
+	for i := range methods.NumMethods() {
+		m := methods.Method(i)
+		sig := m.Type().(*types.Signature)
+		params := []string{}
+		for j := range sig.Params().Len() {
+			p := sig.Params().At(j)
+			paramType := types.TypeString(p.Type(), qualifier)
+			if sig.Variadic() && j == sig.Params().Len()-1 {
+				paramType = "..." + strings.TrimPrefix(paramType, "[]")
+			}
+			params = append(params, strings.TrimSpace(p.Name()+" "+paramType))
+		}
+		results := []string{}
+		for j := range sig.Results().Len() {
+			results = append(results, types.TypeString(sig.Results().At(j).Type(), qualifier))
+		}
+		returns := strings.Join(results, ", ")
+		if len(results) > 1 {
+			returns = "(" + returns + ")"
+		}
+		member := fmt.Sprintf("+ %s(%s) %s", m.Name(), strings.Join(params, ", "), returns)
+		c.Members = append(c.Members, strings.TrimSpace(member))
+	}
+	return c
+}
+
+// addExternalInterfaces draws the implementations of external interfaces together with stub classes for them
+func addExternalInterfaces(d *Diagram, tp *typedPackages, ro map[goplantuml.RenderingOption]any) {
+	if !renderingOption(ro, goplantuml.RenderImplementations, true) {
+		return
+	}
+	edges, interfaces := tp.externalRelations(d, renderingOption(ro, goplantuml.RenderConnectionLabels, false))
+	for _, iface := range interfaces {
+		if d.FindClass(tp.className(iface)) == nil {
+			d.AddClass(interfaceStub(iface), false)
+		}
+	}
+	for _, e := range edges {
+		d.AddEdge(e)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// bufferSource declares types implementing interfaces of the standard library
+const bufferSource = `package buffer
+
+import "fmt"
+
+type Buffer struct {
+	data []byte
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b Buffer) String() string { return fmt.Sprint(b.data) }
+
+type Failure struct{}
+
+func (Failure) Error() string { return "failure" }
+`
+
+func TestSplitInterfaceName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		pkgPath  string
+		typeName string
+		ok       bool
+	}{
+		{name: "standard library", input: "io.Reader", pkgPath: "io", typeName: "Reader", ok: true},
+		{name: "nested import path", input: "net/http.Handler", pkgPath: "net/http", typeName: "Handler", ok: true},
+		{name: "dotted import path", input: "example.com/lib.Doer", pkgPath: "example.com/lib", typeName: "Doer", ok: true},
+		{name: "predeclared interface", input: "error", typeName: "error"},
+		{name: "dot in host only", input: "example.com/lib", typeName: "example.com/lib"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkgPath, typeName, ok := splitInterfaceName(tt.input)
+			if pkgPath != tt.pkgPath || typeName != tt.typeName || ok != tt.ok {
+				t.Errorf("splitInterfaceName() = %v, %v, %v, want %v, %v, %v",
+					pkgPath, typeName, ok, tt.pkgPath, tt.typeName, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAddExternalInterfaces(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":           "module example.com/buffer\n\ngo 1.25\n",
+		"buffer/buffer.go": bufferSource,
+	})
+	dir := filepath.Join(root, "buffer")
+	result, err := goplantuml.NewClassDiagramWithMaxDepth([]string{dir}, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", dir, err)
+	}
+	d := ParseDiagram(result.Render())
+	external := []string{"error", "fmt.Stringer", "io.Writer", "io.Reader"}
+	typed, err := loadTypedPackages([]string{dir}, false, nil, external, nil)
+	if err != nil {
+		t.Fatalf("loadTypedPackages() error = %v", err)
+	}
+	addExternalInterfaces(d, typed, map[goplantuml.RenderingOption]any{})
+
+	expected := []string{
+		"buffer.Failure <|-- error",
+		"buffer.Buffer <|-- fmt.Stringer",
+		"buffer.Buffer <|-- io.Writer",
+	}
+	edges := []string{}
+	for _, e := range d.Edges {
+		if e.Arrow == implementsArrow {
+			edges = append(edges, e.To+" "+e.Arrow+" "+e.From)
+		}
+	}
+	if !equalStrings(edges, expected) {
+		t.Errorf("implementations = %v, want %v", edges, expected)
+	}
+
+	writer := d.FindClass("io.Writer")
+	if writer == nil {
+		t.Fatal("no stub class for io.Writer")
+	}
+	if writer.Kind != "interface" || writer.Stereotypes[0] != externalStereotype {
+		t.Errorf("unexpected stub %+v", writer)
+	}
+	if strings.Join(writer.Members, "|") != "+ Write(p []byte) (int, error)" {
+		t.Errorf("Members = %q", writer.Members)
+	}
+	if d.FindClass("error") == nil {
+		t.Error("no stub class for error")
+	}
+	if d.FindClass("io.Reader") != nil {
+		t.Error("stub class for io.Reader although nothing implements it")
+	}
+}
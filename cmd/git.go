@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runGit runs the git command line tool in a directory and returns its standard output
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...) // #nosec G204 -- the arguments are revisions and paths given by the user
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), message)
+	}
+	return stdout.String(), nil
+}
+
+// isRevision reports whether a name is a revision of the git repository the directory belongs to
+func isRevision(dir, name string) bool {
+	_, err := runGit(dir, "rev-parse", "--verify", "--quiet", name+"^{commit}")
+	return err == nil
+}
+
+// worktree is a temporary checkout of a revision
+type worktree struct {
+	repo string // directory of the repository the worktree belongs to
+	root string // root of the checkout
+	dir  string // directory in the checkout that corresponds to the directory it was checked out from
+}
+
+// remove deletes the checkout and its registration in the repository
+func (w *worktree) remove() {
+	_, _ = runGit(w.repo, "worktree", "remove", "--force", w.root)
+	_ = os.RemoveAll(w.root)
+}
+
+// checkoutRevision checks a revision out into a temporary worktree. The returned directory is the one at the same
+// path relative to the repository root as dir.
+func checkoutRevision(dir, revision string) (*worktree, error) {
+	prefix, err := runGit(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.MkdirTemp("", "go2uml-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %w", err)
+	}
+	w := &worktree{repo: dir, root: root, dir: filepath.Join(root, filepath.FromSlash(strings.TrimSpace(prefix)))}
+	if _, err := runGit(dir, "worktree", "add", "--detach", "--quiet", root, revision); err != nil {
+		_ = os.RemoveAll(root)
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the name of the file read from every input root for additional ignore patterns
+const ignoreFileName = ".go2umlignore"
+
+// ignorePattern is a single gitignore-style pattern such as "**/testdata", "mocks" or "!internal/keep"
+type ignorePattern struct {
+	segments []string
+	negate   bool
+}
+
+// parseIgnorePattern parses one line of an ignore list. It returns false for blank lines and comments.
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+	result := ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		result.negate = true
+		line = line[1:]
+	}
+	line = filepath.ToSlash(line)
+	line = strings.TrimSuffix(line, "/")
+	// A pattern without a slash matches at any depth, one with a slash is anchored at the root
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignorePattern{}, false
+	}
+	if !anchored {
+		line = "**/" + line
+	}
+	result.segments = strings.Split(line, "/")
+	return result, true
+}
+
+// match reports whether the slash separated path, relative to the root, matches the pattern
+func (p ignorePattern) match(rel string) bool {
+	return matchSegments(p.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments where "**" spans any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// isIgnored applies the patterns in order, so a later negated pattern re-includes a path
+func isIgnored(patterns []ignorePattern, rel string) bool {
+	ignored := false
+	for _, p := range patterns {
+		if p.match(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// isGlob reports whether an -ignore entry is a pattern rather than a plain directory
+func isGlob(entry string) bool {
+	return strings.ContainsAny(entry, "*?[") || strings.HasPrefix(entry, "!")
+}
+
+// readIgnoreFile reads the patterns of the .go2umlignore file in root, if there is one
+func readIgnoreFile(root string) ([]ignorePattern, error) {
+	file, err := os.Open(filepath.Join(root, ignoreFileName)) // #nosec G304 -- the root is given by the user
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", ignoreFileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	result := []ignorePattern{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := parseIgnorePattern(scanner.Text()); ok {
+			result = append(result, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", ignoreFileName, err)
+	}
+	return result, nil
+}
+
+// matchIgnoredDirectories walks root and returns the absolute paths of the directories matched by patterns.
+// Matched directories are not descended into, the same way goplantuml skips them.
+func matchIgnoredDirectories(root string, patterns []ignorePattern) ([]string, error) {
+	result := []string{}
+	if len(patterns) == 0 {
+		return result, nil
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if isIgnored(patterns, filepath.ToSlash(rel)) {
+			result = append(result, p)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk directory %s: %w", root, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestIgnorePatternMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "double star prefix at root",
+			pattern:  "**/testdata",
+			path:     "testdata",
+			expected: true,
+		},
+		{
+			name:     "double star prefix nested",
+			pattern:  "**/testdata",
+			path:     "pkg/parser/testdata",
+			expected: true,
+		},
+		{
+			name:     "plain name matches at any depth",
+			pattern:  "mocks",
+			path:     "internal/service/mocks",
+			expected: true,
+		},
+		{
+			name:     "plain name does not match prefix",
+			pattern:  "mocks",
+			path:     "internal/mocksuite",
+			expected: false,
+		},
+		{
+			name:     "anchored single star",
+			pattern:  "internal/*/gen",
+			path:     "internal/api/gen",
+			expected: true,
+		},
+		{
+			name:     "anchored single star does not span segments",
+			pattern:  "internal/*/gen",
+			path:     "internal/api/v1/gen",
+			expected: false,
+		},
+		{
+			name:     "anchored pattern does not match nested",
+			pattern:  "internal/*/gen",
+			path:     "pkg/internal/api/gen",
+			expected: false,
+		},
+		{
+			name:     "leading slash anchors",
+			pattern:  "/gen",
+			path:     "pkg/gen",
+			expected: false,
+		},
+		{
+			name:     "trailing slash is accepted",
+			pattern:  "build/",
+			path:     "tools/build",
+			expected: true,
+		},
+		{
+			name:     "double star in the middle",
+			pattern:  "api/**/gen",
+			path:     "api/v1/admin/gen",
+			expected: true,
+		},
+		{
+			name:     "name glob",
+			pattern:  "*_gen",
+			path:     "pkg/proto_gen",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := parseIgnorePattern(tt.pattern)
+			if !ok {
+				t.Fatalf("parseIgnorePattern(%q) returned no pattern", tt.pattern)
+			}
+			if result := p.match(tt.path); result != tt.expected {
+				t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseIgnorePatternSkipsCommentsAndBlanks(t *testing.T) {
+	for _, line := range []string{"", "   ", "# generated code", "/"} {
+		if _, ok := parseIgnorePattern(line); ok {
+			t.Errorf("parseIgnorePattern(%q) should not return a pattern", line)
+		}
+	}
+}
+
+func TestIsIgnoredNegation(t *testing.T) {
+	patterns := []ignorePattern{}
+	for _, line := range []string{"**/gen", "!api/gen"} {
+		p, _ := parseIgnorePattern(line)
+		patterns = append(patterns, p)
+	}
+	if !isIgnored(patterns, "internal/gen") {
+		t.Error("internal/gen should be ignored")
+	}
+	if isIgnored(patterns, "api/gen") {
+		t.Error("api/gen should be re-included by the negated pattern")
+	}
+}
+
+func TestGetIgnoredDirectories(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"internal/api/gen",
+		"internal/db/gen",
+		"internal/db/model",
+		"pkg/mocks",
+		"pkg/parser/testdata",
+		"pkg/parser/testdata/nested",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignoreFile := "# generated code\ninternal/*/gen\n!internal/db/gen\n"
+	if err := os.WriteFile(filepath.Join(root, ignoreFileName), []byte(ignoreFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := getIgnoredDirectories("**/testdata, **/mocks", []string{root}, true)
+	if err != nil {
+		t.Fatalf("getIgnoredDirectories() error = %v", err)
+	}
+	sort.Strings(result)
+
+	expected := []string{
+		filepath.Join(root, "internal/api/gen"),
+		filepath.Join(root, "pkg/mocks"),
+		filepath.Join(root, "pkg/parser/testdata"),
+	}
+	sort.Strings(expected)
+	if len(result) != len(expected) {
+		t.Fatalf("getIgnoredDirectories() = %v, want %v", result, expected)
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("getIgnoredDirectories() = %v, want %v", result, expected)
+			break
+		}
+	}
+}
+
+func TestGetIgnoredDirectoriesPlainEntries(t *testing.T) {
+	result, err := getIgnoredDirectories("vendor, node_modules", nil, false)
+	if err != nil {
+		t.Fatalf("getIgnoredDirectories() error = %v", err)
+	}
+	for i, dir := range []string{"vendor", "node_modules"} {
+		expected, _ := filepath.Abs(dir)
+		if result[i] != expected {
+			t.Errorf("getIgnoredDirectories()[%d] = %v, want %v", i, result[i], expected)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// majorVersion matches the major version suffix of module paths, e.g. v2 in example.com/lib/v2
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// sourceType is the declaration of a named type of the diagram together with the methods declared for it
+type sourceType struct {
+	name    string
+	file    *ast.File
+	spec    *ast.TypeSpec
+	doc     *ast.CommentGroup
+	methods []*sourceMethod
+}
+
+// sourceMethod is a method declaration and the file it is declared in, whose imports qualify its types
+type sourceMethod struct {
+	decl *ast.FuncDecl
+	file *ast.File
+}
+
+// sourceAssertion is a package level declaration like var _ Iface = (*T)(nil), which asserts that a type
+// implements an interface
+type sourceAssertion struct {
+	namespace string
+	file      *ast.File
+	iface     ast.Expr
+	value     ast.Expr
+}
+
+// sourceIndex holds the declarations of the diagram's types, parsed from the same files goplantuml draws. Types are
+// found by the full name of their class, e.g. "example.User".
+type sourceIndex struct {
+	fset       *token.FileSet
+	types      map[string]*sourceType
+	assertions []*sourceAssertion
+	dirs       map[string]string          // the directory of every namespace
+	imports    map[string]map[string]bool // the import paths of every namespace
+}
+
+// loadSourceIndex parses the collected source files. roots are the input directories, which determine the
+// namespaces the same way they do for goplantuml.
+func loadSourceIndex(sources *sourceFiles, roots []string) *sourceIndex {
+	idx := &sourceIndex{
+		fset:    token.NewFileSet(),
+		types:   map[string]*sourceType{},
+		dirs:    map[string]string{},
+		imports: map[string]map[string]bool{},
+	}
+	for _, dir := range sources.dirs {
+		namespace := namespacePath(dir, roots)
+		idx.dirs[namespace] = dir
+		for _, path := range sources.files[dir] {
+			file, err := parser.ParseFile(idx.fset, path, nil, parser.ParseComments)
+			if err != nil {
+				slog.Warn(
+					"could not parse file, its declarations are left out",
+					"file", filepath.Base(path),
+					"error", err,
+				)
+				continue
+			}
+			idx.addFile(namespace, file)
+		}
+	}
+	return idx
+}
+
+// addFile adds the imports and the type and method declarations of a file
+func (idx *sourceIndex) addFile(namespace string, file *ast.File) {
+	for _, spec := range file.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			if idx.imports[namespace] == nil {
+				idx.imports[namespace] = map[string]bool{}
+			}
+			idx.imports[namespace][importPath] = true
+		}
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok == token.VAR {
+				idx.addAssertions(namespace, file, decl)
+			}
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				t := idx.get(namespace, spec.Name.Name)
+				t.file = file
+				t.spec = spec
+				t.doc = spec.Doc
+				if t.doc == nil && len(decl.Specs) == 1 {
+					t.doc = decl.Doc
+				}
+			}
+		case *ast.FuncDecl:
+			if name := receiverName(decl); name != "" {
+				t := idx.get(namespace, name)
+				t.methods = append(t.methods, &sourceMethod{decl: decl, file: file})
+			}
+		}
+	}
+}
+
+// addAssertions adds the blank variables of a declaration that have an explicit type and a single value
+func (idx *sourceIndex) addAssertions(namespace string, file *ast.File, decl *ast.GenDecl) {
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		if spec.Type == nil || len(spec.Names) != len(spec.Values) {
+			continue
+		}
+		for i, name := range spec.Names {
+			if name.Name == "_" {
+				idx.assertions = append(idx.assertions, &sourceAssertion{
+					namespace: namespace,
+					file:      file,
+					iface:     spec.Type,
+					value:     spec.Values[i],
+				})
+			}
+		}
+	}
+}
+
+// get returns the type of the namespace with the given name, adding it if it was not seen yet
+func (idx *sourceIndex) get(namespace, name string) *sourceType {
+	fullName := namespace + "." + name
+	t, ok := idx.types[fullName]
+	if !ok {
+		t = &sourceType{name: name}
+		idx.types[fullName] = t
+	}
+	return t
+}
+
+// lookup returns the declaration of a class of the diagram, or nil if the class is not declared in the sources
+func (idx *sourceIndex) lookup(c *Class) *sourceType {
+	t := idx.types[c.SourceName()]
+	if t == nil || t.spec == nil {
+		return nil
+	}
+	return t
+}
+
+// importedNamespaces returns the namespaces every namespace imports, leaving out the packages that are not part of
+// the index. Import paths are resolved with the go.mod file of the package directories.
+func (idx *sourceIndex) importedNamespaces() map[string][]string {
+	namespaces := map[string]string{} // namespaces by import path
+	for namespace, dir := range idx.dirs {
+		m, err := findModule(dir)
+		if err != nil {
+			continue
+		}
+		if importPath, err := m.importPath(filepath.Clean(dir)); err == nil {
+			namespaces[importPath] = namespace
+		}
+	}
+	result := map[string][]string{}
+	for namespace, imports := range idx.imports {
+		for importPath := range imports {
+			if imported, ok := namespaces[importPath]; ok && imported != namespace {
+				result[namespace] = append(result[namespace], imported)
+			}
+		}
+	}
+	return result
+}
+
+// receiverName returns the name of the type a method is declared for, or an empty string for functions
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		expr = e.X
+	case *ast.IndexListExpr:
+		expr = e.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// classResolver finds the classes of the diagram that type expressions of the sources refer to
+type classResolver struct {
+	byName    map[string]*Class   // classes by their full name in the package they are declared in
+	byPackage map[string][]*Class // classes by the last element of their namespace and their name, e.g. "api.Handler"
+}
+
+// newClassResolver indexes the classes of the diagram
+func newClassResolver(d *Diagram) *classResolver {
+	r := &classResolver{byName: map[string]*Class{}, byPackage: map[string][]*Class{}}
+	for _, c := range d.AllClasses() {
+		r.byName[c.SourceName()] = c
+		key := shortName(c.SourceNamespace()) + "." + c.Name
+		r.byPackage[key] = append(r.byPackage[key], c)
+	}
+	return r
+}
+
+// resolve returns the class a type name refers to, or nil if it is not part of the diagram. namespace is the one
+// of the declaring type and file the file the expression is written in. Qualified names are matched by the name of
+// the imported package, which must identify a single class.
+func (r *classResolver) resolve(namespace string, file *ast.File, expr ast.Expr) *Class {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return r.byName[namespace+"."+e.Name]
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if importName(spec, importPath) != x.Name {
+				continue
+			}
+			if candidates := r.byPackage[path.Base(importPath)+"."+e.Sel.Name]; len(candidates) == 1 {
+				return candidates[0]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// importName returns the name an import is referred to by in its file, assuming that packages are named like the
+// last element of their import path without a major version suffix
+func importName(spec *ast.ImportSpec, importPath string) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	base := path.Base(importPath)
+	if majorVersion.MatchString(base) && strings.Contains(importPath, "/") {
+		base = path.Base(path.Dir(importPath))
+	}
+	return strings.TrimPrefix(base, "go-")
+}
+
+// typeNames returns the identifiers and qualified identifiers of the named types a type expression refers to
+func typeNames(expr ast.Expr) []ast.Expr {
+	result := []ast.Expr{}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			result = append(result, typeNames(n.Type)...)
+			return false
+		case *ast.SelectorExpr:
+			result = append(result, n)
+			return false
+		case *ast.Ident:
+			result = append(result, n)
+		}
+		return true
+	})
+	return result
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -33,92 +31,52 @@ func (as RenderingOptionSlice) Swap(i, j int) {
 	as[i], as[j] = as[j], as[i]
 }
 
+// usage is the synopsis of go2uml printed before its flags. The first argument diff runs go2uml diff, a directory
+// named diff is drawn when it is given as ./diff or after --.
+const usage = `usage: go2uml [flags] <DIR|./...|IMPORTPATH>...
+       go2uml diff [-format=plantuml|mermaid] [-output=FILE] <old> <new>
+
+To draw a directory named diff, write go2uml ./diff or go2uml -- diff.
+
+flags:
+`
+
 func main() {
-	recursive := flag.Bool("recursive", false, "walk all directories recursively")
-	ignore := flag.String("ignore", "", "comma separated list of folders to ignore")
-	maxDepth := flag.Int("max-depth", 0, "maximum nesting depth for packages (0 = unlimited)")
-	showAggregations := flag.Bool(
-		"show-aggregations",
-		false,
-		"renders public aggregations even when -hide-connections is used (do not render by default)",
-	)
-	hideFields := flag.Bool("hide-fields", false, "hides fields")
-	hideMethods := flag.Bool("hide-methods", false, "hides methods")
-	hideConnections := flag.Bool("hide-connections", false, "hides all connections in the diagram")
-	showCompositions := flag.Bool("show-compositions", false, "Shows compositions even when -hide-connections is used")
-	showImplementations := flag.Bool(
-		"show-implementations",
-		false,
-		"Shows implementations even when -hide-connections is used",
-	)
-	showAliases := flag.Bool("show-aliases", false, "Shows aliases even when -hide-connections is used")
-	showConnectionLabels := flag.Bool(
-		"show-connection-labels",
-		false,
-		"Shows labels in the connections to identify the connections types (e.g. extends, implements, aggregates, alias of",
-	)
-	title := flag.String("title", "", "Title of the generated diagram")
-	notes := flag.String("notes", "", "Comma separated list of notes to be added to the diagram")
-	output := flag.String("output", "", "output file path. If omitted, then this will default to standard output")
-	showOptionsAsNote := flag.Bool(
-		"show-options-as-note",
-		false,
-		"Show a note in the diagram with the none evident options ran with this CLI",
-	)
-	aggregatePrivateMembers := flag.Bool(
-		"aggregate-private-members",
-		false,
-		"Show aggregations for private members. Ignored if -show-aggregations is not used.",
-	)
-	hidePrivateMembers := flag.Bool("hide-private-members", false, "Hide private fields and methods")
-	format := flag.String("format", "plantuml", "output format: plantuml or mermaid (mermaid support is experimental)")
-	flag.Parse()
-	renderingOptions := map[goplantuml.RenderingOption]any{
-		goplantuml.RenderConnectionLabels:  *showConnectionLabels,
-		goplantuml.RenderFields:            !*hideFields,
-		goplantuml.RenderMethods:           !*hideMethods,
-		goplantuml.RenderAggregations:      *showAggregations,
-		goplantuml.RenderTitle:             *title,
-		goplantuml.AggregatePrivateMembers: *aggregatePrivateMembers,
-		goplantuml.RenderPrivateMembers:    !*hidePrivateMembers,
-	}
-	if *hideConnections {
-		renderingOptions[goplantuml.RenderAliases] = *showAliases
-		renderingOptions[goplantuml.RenderCompositions] = *showCompositions
-		renderingOptions[goplantuml.RenderImplementations] = *showImplementations
-
-	}
-	noteList := []string{}
-	if *showOptionsAsNote {
-		legend, err := getLegend(renderingOptions)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			os.Exit(1)
-		}
-		noteList = append(noteList, legend)
+	if isDiffCommand(os.Args[1:]) {
+		os.Exit(runDiff(os.Args[2:], os.Stdout, os.Stderr))
 	}
-	if *notes != "" {
-		noteList = append(noteList, "", "<b><u>Notes</u></b>")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
 	}
-	split := strings.Split(*notes, ",")
-	for _, note := range split {
-		trimmed := strings.TrimSpace(note)
-		if trimmed != "" {
-			noteList = append(noteList, trimmed)
-		}
+	opts := defineFlags(flag.CommandLine)
+	flag.Parse()
+	if format := strings.ToLower(opts.format); format != "plantuml" && format != "mermaid" {
+		fmt.Println("usage:\ngoplantuml [-format=plantuml|mermaid]\nformat must be plantuml or mermaid")
+		fmt.Fprintln(os.Stderr, "format must be plantuml or mermaid")
+		os.Exit(1)
 	}
-	renderingOptions[goplantuml.RenderNotes] = strings.Join(noteList, "\n")
-	dirs, err := getDirectories()
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	selection, err := getDirectories(flag.Args(), opts.recursive)
 
 	if err != nil {
-		slog.Error("DIR Must be a valid directory", "usage", "goplantuml <DIR>", "error", err)
+		slog.Error(
+			"DIR Must be a valid directory, package pattern or import path",
+			"usage",
+			"goplantuml <DIR|./...|IMPORTPATH>",
+			"error",
+			err,
+		)
 		os.Exit(1)
 	}
-	ignoredDirectories, err := getIgnoredDirectories(*ignore)
+	ignoredDirectories, err := getIgnoredDirectories(opts.ignore, selection.dirs, selection.recursive)
 	if err != nil {
 
 		slog.Error(
-			"DIRLIST Must be a valid comma separated list of existing directories",
+			"DIRLIST Must be a valid comma separated list of existing directories or patterns",
 			"usage",
 			"goplantuml [-ignore=<DIRLIST>]",
 			"error",
@@ -127,82 +85,93 @@ func main() {
 		os.Exit(1)
 	}
 
-	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, ignoredDirectories, *recursive, *maxDepth)
+	ignoredDirectories = append(ignoredDirectories, selection.ignored...)
+
+	p, err := newPipeline(opts, selection, ignoredDirectories)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
-	if result == nil {
-		fmt.Fprintln(os.Stderr, "No classes found to generate diagram")
+	rendered, err := p.render()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
-	_ = result.SetRenderingOptions(renderingOptions)
-
-	rendered := result.Render()
-	switch strings.ToLower(*format) {
-	case "plantuml":
-		// do nothing, plantuml is the default
-	case "mermaid":
-		rendered, err = ConvertToMermaid(rendered)
-		if err != nil {
+	var parts []*Diagram
+	violations := []*violation{}
+	if p.transforms() {
+		diagram := ParseDiagram(rendered)
+		if violations, err = transformDiagram(diagram, p); err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(1)
 		}
-	default:
-		fmt.Println("usage:\ngoplantuml [-format=plantuml|mermaid]\nformat must be plantuml or mermaid")
-		fmt.Fprintln(os.Stderr, "format must be plantuml or mermaid")
-		os.Exit(1)
-	}
-
-	var writer io.Writer
-	if *output != "" {
-		writer, err = os.Create(*output)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-		}
-	} else {
-		writer = os.Stdout
-	}
-	_, _ = fmt.Fprint(writer, rendered)
-}
-
-func getDirectories() ([]string, error) {
-
-	args := flag.Args()
-	if len(args) < 1 {
-		return nil, errors.New("DIR missing")
-	}
-	dirs := []string{}
-	for _, dir := range args {
-		fi, err := os.Stat(dir)
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("could not find directory %s", dir)
+		if opts.check != "" && !opts.markViolations {
+			if len(violations) > 0 {
+				os.Exit(1)
+			}
+			return
 		}
-		if !fi.Mode().IsDir() {
-			return nil, fmt.Errorf("%s is not a directory", dir)
+		if opts.splitBy != "" {
+			if err := writeSplit(diagram, opts.outputDir, strings.ToLower(opts.format), p.limits); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			if len(violations) > 0 {
+				os.Exit(1)
+			}
+			return
 		}
-		dirAbs, err := filepath.Abs(dir)
-		if err != nil {
-			return nil, fmt.Errorf("could not find directory %s", dir)
+		if parts = partitionDiagram(diagram, p.limits); len(parts) > 1 {
+			slog.Warn("diagram exceeds -max-nodes or -max-edges, it is split into parts", "parts", len(parts))
 		}
-		dirs = append(dirs, dirAbs)
+		rendered = diagram.Render()
+	}
+	if err := writeDiagram(rendered, parts, opts.format, opts.output); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
 	}
-	return dirs, nil
 }
 
-func getIgnoredDirectories(list string) ([]string, error) {
+// getIgnoredDirectories resolves the -ignore list and the .go2umlignore file of every root into absolute directories.
+// Plain entries are directories relative to the working directory, entries containing glob characters are
+// gitignore-style patterns matched below every root.
+func getIgnoredDirectories(list string, roots []string, recursive bool) ([]string, error) {
 	result := []string{}
+	patterns := []ignorePattern{}
 	list = strings.TrimSpace(list)
-	if list == "" {
+	if list != "" {
+		split := strings.Split(list, ",")
+		for _, dir := range split {
+			dir = strings.TrimSpace(dir)
+			if isGlob(dir) {
+				if p, ok := parseIgnorePattern(dir); ok {
+					patterns = append(patterns, p)
+				}
+				continue
+			}
+			dirAbs, err := filepath.Abs(dir)
+			if err != nil {
+				return nil, fmt.Errorf("could not find directory %s", dir)
+			}
+			result = append(result, dirAbs)
+		}
+	}
+	if !recursive {
 		return result, nil
 	}
-	split := strings.Split(list, ",")
-	for _, dir := range split {
-		dirAbs, err := filepath.Abs(strings.TrimSpace(dir))
+	for _, root := range roots {
+		filePatterns, err := readIgnoreFile(root)
 		if err != nil {
-			return nil, fmt.Errorf("could not find directory %s", dir)
+			return nil, err
 		}
-		result = append(result, dirAbs)
+		matched, err := matchIgnoredDirectories(root, append(filePatterns, patterns...))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, matched...)
 	}
 	return result, nil
 }
@@ -248,11 +217,53 @@ func ConvertToMermaid(plantUML string) (string, error) {
 	classTypes := make(map[string]string)       // className -> "class" or "interface"
 	classNameMapping := make(map[string]string) // full name -> simple name
 	insideClass := false
+	insideGroup := false
 	currentNamespace := ""
+	var styles []string
+	relationships := 0 // number of relationships written so far, linkStyle refers to them by index
+	noteTarget := ""
+	var noteLines []string
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 
+		// Handle notes attached to classes, their text is not PlantUML
+		if noteTarget != "" {
+			if line != "end note" {
+				noteLines = append(noteLines, line)
+				continue
+			}
+			// Notes of members are attached to their class, Mermaid has no member notes
+			class, member, isMember := strings.Cut(noteTarget, "::")
+			target := classNameMapping[cleanClassName(class)]
+			if target == "" {
+				target = cleanClassName(class)
+			}
+			if isMember && len(noteLines) > 0 {
+				noteLines[0] = member + ": " + noteLines[0]
+			}
+			text := strings.ReplaceAll(strings.Join(noteLines, "<br>"), "\"", "'")
+			mermaidLines = append(mermaidLines, fmt.Sprintf("    note for %s \"%s\"", target, text))
+			noteTarget = ""
+			noteLines = nil
+			continue
+		}
+		if strings.HasPrefix(line, "note ") && strings.Contains(line, " of ") {
+			_, noteTarget, _ = strings.Cut(line, " of ")
+			noteTarget = strings.TrimSpace(noteTarget)
+			continue
+		}
+
+		// Remove links and tooltips, Mermaid declares them separately
+		link := ""
+		if start := strings.Index(line, "[["); start >= 0 {
+			if end := strings.Index(line[start:], "]]"); end >= 0 {
+				link, _, _ = strings.Cut(line[start+2:start+end], "{")
+				link = strings.TrimSpace(link)
+				line = line[:start] + line[start+end+2:]
+			}
+		}
+
 		if strings.Contains(line, "<<") && strings.Contains(line, ">>") {
 			start := strings.Index(line, "<<")
 			end := strings.Index(line, ">>")
@@ -278,6 +289,17 @@ func ConvertToMermaid(plantUML string) (string, error) {
 			continue
 		}
 
+		// Handle groups, they are the only namespaces Mermaid draws
+		if strings.HasPrefix(line, "package ") {
+			parts := strings.Fields(line)
+			if len(parts) > 1 {
+				currentNamespace = parts[1]
+				insideGroup = true
+				mermaidLines = append(mermaidLines, fmt.Sprintf("    namespace %s {", currentNamespace))
+			}
+			continue
+		}
+
 		// Handle interface definitions
 		if strings.Contains(line, "interface ") && strings.Contains(line, " {") {
 			interfaceName := extractClassName(line)
@@ -287,8 +309,22 @@ func ConvertToMermaid(plantUML string) (string, error) {
 				fullName := currentNamespace + "." + interfaceName
 				classTypes[cleanName] = "interface"
 				classNameMapping[cleanClassName(fullName)] = cleanName
+				declaration := parseClassDeclaration(line)
+				if declaration.Alias != "" {
+					classNameMapping[cleanClassName(declaration.Alias)] = cleanName
+				}
+				styles = append(styles, classStyles(cleanName, declaration)...)
+				if link != "" {
+					styles = append(styles, fmt.Sprintf("    click %s href \"%s\"", cleanName, link))
+				}
 				mermaidLines = append(mermaidLines, fmt.Sprintf("    class %s {", cleanName))
-				mermaidLines = append(mermaidLines, "        <<interface>>")
+				stereotypes := plainStereotypes(declaration)
+				if len(stereotypes) == 0 {
+					stereotypes = []string{"interface"}
+				}
+				for _, stereotype := range stereotypes {
+					mermaidLines = append(mermaidLines, fmt.Sprintf("        <<%s>>", stereotype))
+				}
 				insideClass = true
 			}
 			continue
@@ -302,11 +338,23 @@ func ConvertToMermaid(plantUML string) (string, error) {
 				fullName := currentNamespace + "." + className
 				classTypes[cleanName] = "class"
 				classNameMapping[cleanClassName(fullName)] = cleanName
+				declaration := parseClassDeclaration(line)
+				if declaration.Alias != "" {
+					classNameMapping[cleanClassName(declaration.Alias)] = cleanName
+				}
 
-				// Check for stereotypes
-				stereotype := extractStereotype(line)
+				// Check for stereotypes, Mermaid only shows the first one so stereotypes given in the sources replace
+				// the kind of the class
+				styles = append(styles, classStyles(cleanName, declaration)...)
+				if link != "" {
+					styles = append(styles, fmt.Sprintf("    click %s href \"%s\"", cleanName, link))
+				}
+				stereotypes := plainStereotypes(declaration)
+				if stereotype := extractStereotype(line); len(stereotypes) == 0 && stereotype != "" {
+					stereotypes = []string{stereotype}
+				}
 				mermaidLines = append(mermaidLines, fmt.Sprintf("    class %s {", cleanName))
-				if stereotype != "" {
+				for _, stereotype := range stereotypes {
 					mermaidLines = append(mermaidLines, fmt.Sprintf("        <<%s>>", stereotype))
 				}
 				insideClass = true
@@ -324,6 +372,17 @@ func ConvertToMermaid(plantUML string) (string, error) {
 			continue
 		}
 
+		// Handle the lines counting the members left out by -max-members, the types of collapsed packages and the
+		// metrics of -metrics, Mermaid has no compartments so their separator is left out
+		if insideClass && line == metricsSeparator {
+			continue
+		}
+		if insideClass &&
+			(strings.HasPrefix(line, "... ") || typeCountLine.MatchString(line) || metricsLine.MatchString(line)) {
+			mermaidLines = append(mermaidLines, fmt.Sprintf("        %s", line))
+			continue
+		}
+
 		// Handle constraints lines (for generic type parameters)
 		if strings.Contains(line, "constraints:") {
 			// Skip constraints in Mermaid as they don't have direct equivalent
@@ -338,25 +397,87 @@ func ConvertToMermaid(plantUML string) (string, error) {
 			} else if currentNamespace != "" {
 				// Exiting namespace
 				currentNamespace = ""
+				if insideGroup {
+					mermaidLines = append(mermaidLines, "    }")
+					insideGroup = false
+				}
 			}
 			continue
 		}
 
 		// Handle relationships (outside of class definitions)
-		if !insideClass && (strings.Contains(line, "<|--") || strings.Contains(line, "*--") ||
-			strings.Contains(line, "<--") || strings.Contains(line, "--") ||
-			strings.Contains(line, "<..") || strings.Contains(line, "..>")) {
+		if edge := parseRelationship(line); !insideClass && edge != nil {
 			relationship := convertRelationshipWithMapping(line, classNameMapping)
 			if relationship != "" {
 				mermaidLines = append(mermaidLines, fmt.Sprintf("    %s", relationship))
+				if color := arrowColor(edge.Arrow); color != "" {
+					style := fmt.Sprintf("    linkStyle %d stroke:%s", relationships, mermaidColor(color))
+					styles = append(styles, style)
+				}
+				relationships++
 			}
 			continue
 		}
 	}
 
+	mermaidLines = append(mermaidLines, styles...)
 	return strings.Join(mermaidLines, "\n"), nil
 }
 
+// arrowColor returns the inline color of a relationship arrow without its #, e.g. red for -[#red]-
+func arrowColor(arrow string) string {
+	start, end := strings.Index(arrow, "[#"), strings.Index(arrow, "]")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arrow[start+2 : end]
+}
+
+// plainStereotypes returns the stereotypes of a class declaration that are not goplantuml's spots like (S,Aquamarine)
+// or type parameters like [T]
+func plainStereotypes(c *Class) []string {
+	result := []string{}
+	for _, s := range c.Stereotypes {
+		s = strings.TrimSpace(s)
+		if s != "" && !strings.HasPrefix(s, "(") && !strings.HasPrefix(s, "[") {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// classStyles returns Mermaid style statements for the colors of a class declaration, e.g. #ffeeaa, #LightBlue or
+// #LightBlue;line:red for a background with a colored border
+func classStyles(name string, c *Class) []string {
+	result := []string{}
+	for _, field := range strings.Fields(c.Extra) {
+		color, ok := strings.CutPrefix(field, "#")
+		if !ok || color == "" {
+			continue
+		}
+		properties := []string{}
+		for _, part := range strings.Split(color, ";") {
+			if line, ok := strings.CutPrefix(part, "line:"); ok {
+				properties = append(properties, "stroke:"+mermaidColor(line))
+			} else if part != "" {
+				properties = append(properties, "fill:"+mermaidColor(part))
+			}
+		}
+		if len(properties) > 0 {
+			result = append(result, fmt.Sprintf("    style %s %s", name, strings.Join(properties, ",")))
+		}
+	}
+	return result
+}
+
+// mermaidColor returns a PlantUML color as a CSS color, hexadecimal colors get their # back and names are lower cased
+func mermaidColor(color string) string {
+	if strings.Trim(strings.ToLower(color), "0123456789abcdef") == "" {
+		return "#" + color
+	}
+	return strings.ToLower(color)
+}
+
 // extractClassName extracts the class name from a class or interface definition line
 func extractClassName(line string) string {
 	// Handle various patterns like:
@@ -472,9 +593,17 @@ func convertFieldOrMethod(line string) string {
 	// Remove leading + - # symbols and convert to Mermaid syntax
 	line = strings.TrimSpace(line)
 
-	// Remove HTML color tags
+	// Remove HTML color tags and strike-through
 	line = strings.ReplaceAll(line, "<font color=blue>", "")
 	line = strings.ReplaceAll(line, "</font>", "")
+	line = strings.ReplaceAll(line, "<s>", "")
+	line = strings.ReplaceAll(line, "</s>", "")
+	line = diffMarkup.ReplaceAllString(line, "")
+
+	// Keep struct tags as a plain suffix, Mermaid has no stereotypes on members
+	if start := strings.Index(line, " <<"); start >= 0 && strings.HasSuffix(line, ">>") {
+		line = line[:start] + " " + strings.NewReplacer("\"", "", ">> <<", " ").Replace(line[start+3:len(line)-2])
+	}
 
 	if strings.HasPrefix(line, "+ ") {
 		return "+" + strings.TrimSpace(line[2:])
@@ -489,101 +618,88 @@ func convertFieldOrMethod(line string) string {
 
 // convertRelationshipWithMapping converts PlantUML relationships to Mermaid syntax using class name mapping
 func convertRelationshipWithMapping(line string, classNameMapping map[string]string) string {
-	line = strings.TrimSpace(line)
-
-	// Handle inheritance: A <|-- B becomes B --|> A
-	if strings.Contains(line, "<|--") {
-		parts := strings.Split(line, "<|--")
-		if len(parts) == 2 {
-			parentUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[0], "\"", ""))
-			childUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[1], "\"", ""))
-
-			parentFull := cleanClassName(parentUnquoted)
-			childFull := cleanClassName(childUnquoted)
-
-			// Map to simple names if available
-			parent := classNameMapping[parentFull]
-			child := classNameMapping[childFull]
-			if parent == "" {
-				parent = parentFull
-			}
-			if child == "" {
-				child = childFull
-			}
+	edge := parseRelationship(line)
+	if edge == nil {
+		return ""
+	}
 
-			return fmt.Sprintf("%s --|> %s", child, parent)
+	// Map to simple names if available
+	mapName := func(name string) string {
+		full := cleanClassName(name)
+		if simple := classNameMapping[full]; simple != "" {
+			return simple
 		}
+		return full
 	}
-
-	// Handle composition: A *-- B becomes A *-- B
-	if strings.Contains(line, "*--") {
-		parts := strings.Split(line, "*--")
-		if len(parts) == 2 {
-			leftUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[0], "\"", ""))
-			rightUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[1], "\"", ""))
-
-			leftFull := cleanClassName(leftUnquoted)
-			rightFull := cleanClassName(rightUnquoted)
-
-			left := classNameMapping[leftFull]
-			right := classNameMapping[rightFull]
-			if left == "" {
-				left = leftFull
-			}
-			if right == "" {
-				right = rightFull
-			}
-
-			return fmt.Sprintf("%s *-- %s", left, right)
+	left, right := mapName(edge.From), mapName(edge.To)
+
+	// Quoted texts next to the classes are kept as cardinalities if they are multiplicities, other texts such as
+	// connection labels become the label of the relationship
+	leftText, rightText, label := edge.FromLabel, edge.ToLabel, edge.Label
+	if !isMultiplicity(leftText) {
+		if label == "" {
+			label = leftText
 		}
+		leftText = ""
 	}
-
-	// Handle dependency: A <-- B becomes A <-- B
-	if strings.Contains(line, "<--") && !strings.Contains(line, "<|--") {
-		parts := strings.Split(line, "<--")
-		if len(parts) == 2 {
-			leftUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[0], "\"", ""))
-			rightUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[1], "\"", ""))
-
-			leftFull := cleanClassName(leftUnquoted)
-			rightFull := cleanClassName(rightUnquoted)
-
-			left := classNameMapping[leftFull]
-			right := classNameMapping[rightFull]
-			if left == "" {
-				left = leftFull
-			}
-			if right == "" {
-				right = rightFull
-			}
-
-			return fmt.Sprintf("%s <-- %s", left, right)
+	if !isMultiplicity(rightText) {
+		if label == "" {
+			label = rightText
 		}
+		rightText = ""
 	}
 
-	// Handle association: A -- B becomes A -- B
-	if strings.Contains(line, "--") && !strings.Contains(line, "<--") && !strings.Contains(line, "*--") &&
-		!strings.Contains(line, "<|--") {
-		parts := strings.Split(line, "--")
-		if len(parts) == 2 {
-			leftUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[0], "\"", ""))
-			rightUnquoted := strings.TrimSpace(strings.ReplaceAll(parts[1], "\"", ""))
+	// Remove inline styles like -[#red]-, Mermaid styles relationships separately
+	arrow := edge.Arrow
+	if start := strings.Index(arrow, "["); start >= 0 {
+		if end := strings.Index(arrow, "]"); end > start {
+			arrow = arrow[:start] + arrow[end+1:]
+		}
+	}
 
-			leftFull := cleanClassName(leftUnquoted)
-			rightFull := cleanClassName(rightUnquoted)
+	switch arrow {
+	case "<|--":
+		// Handle inheritance: A <|-- B becomes B --|> A
+		left, right, leftText, rightText, arrow = right, left, rightText, leftText, "--|>"
+	case "<|..":
+		// Handle realization: A <|.. B becomes B ..|> A
+		left, right, leftText, rightText, arrow = right, left, rightText, leftText, "..|>"
+	case "*--", "o--", "<--", "-->", "--", "<..", "..>", "..":
+		// Composition, aggregation, association and dependency use the same arrows in Mermaid
+	default:
+		return ""
+	}
 
-			left := classNameMapping[leftFull]
-			right := classNameMapping[rightFull]
-			if left == "" {
-				left = leftFull
-			}
-			if right == "" {
-				right = rightFull
-			}
+	parts := []string{left}
+	if leftText != "" {
+		parts = append(parts, `"`+leftText+`"`)
+	}
+	parts = append(parts, arrow)
+	if rightText != "" {
+		parts = append(parts, `"`+rightText+`"`)
+	}
+	parts = append(parts, right)
+	if label != "" {
+		parts = append(parts, ":", label)
+	}
+	return strings.Join(parts, " ")
+}
 
-			return fmt.Sprintf("%s -- %s", left, right)
-		}
+// parseRelationship parses a relationship line of goplantuml with quoted class names, or a hand-written one with
+// unquoted names like A <|-- B
+func parseRelationship(line string) *Edge {
+	if edge := ParseEdge(line); edge != nil {
+		return edge
+	}
+	relationship, label, _ := strings.Cut(line, ":")
+	fields := strings.Fields(relationship)
+	if len(fields) != 3 || !isArrow(fields[1]) {
+		return nil
 	}
+	return &Edge{From: fields[0], Arrow: fields[1], To: fields[2], Label: strings.TrimSpace(label)}
+}
 
-	return ""
+// isMultiplicity reports whether a relationship text is a UML multiplicity such as 1, 0..1 or *
+func isMultiplicity(text string) bool {
+	return text != "" && strings.Trim(text, "0123456789.*n") == ""
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// Member orders of -sort-members
+const (
+	sortSource     = "source"
+	sortAlpha      = "alpha"
+	sortVisibility = "visibility"
+)
+
+// memberOrders are the values -sort-members accepts
+var memberOrders = []string{sortSource, sortAlpha, sortVisibility}
+
+// isMethod reports whether a member line is a method
+func isMethod(member string) bool {
+	text := strings.TrimPrefix(strings.TrimLeft(member, "+-#~ "), "<s>")
+	return strings.HasPrefix(strings.TrimPrefix(text, memberName(member)), "(")
+}
+
+// isMember reports whether a body line is a field or method with a visibility
+func isMember(line string) bool {
+	return line != "" && strings.ContainsRune("+-#~", rune(line[0]))
+}
+
+// memberPositions returns the order fields and methods are declared in, by member name
+func memberPositions(t *sourceType) map[string]int {
+	result := map[string]int{}
+	add := func(name string) {
+		if _, ok := result[name]; !ok {
+			result[name] = len(result)
+		}
+	}
+	var fields []*ast.Field
+	switch spec := t.spec.Type.(type) {
+	case *ast.StructType:
+		fields = spec.Fields.List
+	case *ast.InterfaceType:
+		fields = spec.Methods.List
+	}
+	for _, field := range fields {
+		for _, name := range field.Names {
+			add(name.Name)
+		}
+	}
+	for _, m := range t.methods {
+		add(m.decl.Name.Name)
+	}
+	return result
+}
+
+// sortMembers orders the fields and the methods of every class: in the order they are declared in the sources,
+// by name, or by name with public members before private ones. Fields stay in front of methods. Classes with
+// body lines that are not members, such as the constraints of type parameters, are left as they are.
+func sortMembers(d *Diagram, idx *sourceIndex, order string) {
+	for _, c := range d.AllClasses() {
+		var fields, methods []string
+		plain := true
+		for _, m := range c.Members {
+			switch {
+			case m == "":
+			case !isMember(m):
+				plain = false
+			case isMethod(m):
+				methods = append(methods, m)
+			default:
+				fields = append(fields, m)
+			}
+		}
+		if !plain {
+			continue
+		}
+
+		byName := func(members []string) {
+			sort.SliceStable(members, func(i, j int) bool {
+				return strings.ToLower(memberName(members[i])) < strings.ToLower(memberName(members[j]))
+			})
+		}
+		groups := [][]string{fields, methods}
+		switch order {
+		case sortSource:
+			t := idx.lookup(c)
+			if t == nil {
+				continue
+			}
+			positions := memberPositions(t)
+			position := func(m string) int {
+				if p, ok := positions[memberName(m)]; ok {
+					return p
+				}
+				return len(positions)
+			}
+			for _, members := range groups {
+				sort.SliceStable(members, func(i, j int) bool { return position(members[i]) < position(members[j]) })
+			}
+		case sortAlpha:
+			byName(fields)
+			byName(methods)
+		case sortVisibility:
+			groups = nil
+			for _, members := range [][]string{fields, methods} {
+				byName(members)
+				var public, private []string
+				for _, m := range members {
+					if strings.HasPrefix(m, "+") {
+						public = append(public, m)
+					} else {
+						private = append(private, m)
+					}
+				}
+				groups = append(groups, public, private)
+			}
+		}
+
+		c.Members = []string{}
+		for _, members := range groups {
+			if len(members) > 0 {
+				c.Members = append(c.Members, members...)
+				c.Members = append(c.Members, "")
+			}
+		}
+	}
+}
+
+// truncateMembers keeps the first limit members of every class and replaces the others by a line like
+// "... 37 more"
+func truncateMembers(d *Diagram, limit int) {
+	for _, c := range d.AllClasses() {
+		count := 0
+		for _, m := range c.Members {
+			if m != "" {
+				count++
+			}
+		}
+		if count <= limit {
+			continue
+		}
+		kept := []string{}
+		shown := 0
+		for _, m := range c.Members {
+			if shown == limit {
+				break
+			}
+			if m != "" {
+				shown++
+			}
+			kept = append(kept, m)
+		}
+		c.Members = append(kept, fmt.Sprintf("... %d more", count-limit), "")
+	}
+}
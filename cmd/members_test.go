@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// unsortedMembers are the members of a class the way goplantuml emits them: private fields, public fields,
+// private methods and public methods, with methods in the order the files were parsed
+var unsortedMembers = []string{
+	"- name string",
+	"",
+	"+ Zone string",
+	"+ ID int",
+	"",
+	"- save() error",
+	"",
+	"+ Load(id int) error",
+	"+ <s>Get() string</s> <<deprecated>>",
+	"",
+}
+
+func TestSortMembers(t *testing.T) {
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{
+			order: sortAlpha,
+			expected: []string{
+				"+ ID int", "- name string", "+ Zone string", "",
+				"+ <s>Get() string</s> <<deprecated>>", "+ Load(id int) error", "- save() error", "",
+			},
+		},
+		{
+			order: sortVisibility,
+			expected: []string{
+				"+ ID int", "+ Zone string", "",
+				"- name string", "",
+				"+ <s>Get() string</s> <<deprecated>>", "+ Load(id int) error", "",
+				"- save() error", "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			c := &Class{Kind: "class", Name: "Store", Namespace: "store", Members: append([]string{}, unsortedMembers...)}
+			d := &Diagram{Namespaces: []*Namespace{{Name: "store", Path: "store", Classes: []*Class{c}}}}
+			sortMembers(d, nil, tt.order)
+			if !equalStrings(c.Members, tt.expected) {
+				t.Errorf("Members = %q, want %q", c.Members, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSortMembersSource(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"store/store.go": `package store
+
+type Store struct {
+	Zone string
+	name string
+	ID   int
+}
+
+func (s *Store) save() error { return nil }
+`,
+		"store/load.go": `package store
+
+func (s *Store) Load(id int) error { return nil }
+
+func (s *Store) Get() string { return "" }
+`,
+	})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	_ = result.SetRenderingOptions(map[goplantuml.RenderingOption]any{goplantuml.RenderPrivateMembers: true})
+	d := ParseDiagram(result.Render())
+	sortMembers(d, loadSourceIndex(sources, dirs), sortSource)
+
+	expected := []string{
+		"+ Zone string", "- name string", "+ ID int", "",
+		"+ Load(id int) error", "+ Get() string", "- save() error", "",
+	}
+	if members := d.FindClass("store.Store").Members; !equalStrings(members, expected) {
+		t.Errorf("Members = %q, want %q", members, expected)
+	}
+}
+
+func TestTruncateMembers(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "truncated",
+			limit:    3,
+			expected: []string{"- name string", "", "+ Zone string", "+ ID int", "... 3 more", ""},
+		},
+		{
+			name:     "within the limit",
+			limit:    6,
+			expected: unsortedMembers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Class{Kind: "class", Name: "Store", Members: append([]string{}, unsortedMembers...)}
+			d := &Diagram{Classes: []*Class{c}}
+			truncateMembers(d, tt.limit)
+			if !equalStrings(c.Members, tt.expected) {
+				t.Errorf("Members = %q, want %q", c.Members, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Metrics of -metrics, named like in -metrics-thresholds and the header of -metrics-csv
+const (
+	metricFanIn      = "fan-in"
+	metricFanOut     = "fan-out"
+	metricMethods    = "methods"
+	metricFields     = "fields"
+	metricLines      = "lines"
+	metricComplexity = "complexity"
+)
+
+const (
+	// metricsSeparator starts the compartment of a class that shows its metrics
+	metricsSeparator = ".. metrics .."
+	// hotspotColor is the background of classes with a metric above its threshold
+	hotspotColor = "#LightCoral"
+)
+
+var (
+	// metricNames are the metrics in the order they are shown and exported
+	metricNames = []string{metricFanIn, metricFanOut, metricMethods, metricFields, metricLines, metricComplexity}
+	// metricsLine matches the lines of the metrics compartment
+	metricsLine = regexp.MustCompile(`^(fan-in|methods|lines): `)
+)
+
+// typeMetrics are the metrics of one class declared in the sources
+type typeMetrics struct {
+	class      *Class
+	fanIn      int     // number of classes that depend on the class
+	fanOut     int     // number of classes the class depends on
+	methods    int     // declared methods, or the methods of an interface
+	fields     int     // fields of a struct, embedded ones included
+	lines      int     // lines of the type declaration and its methods
+	complexity float64 // average cyclomatic complexity of the methods
+}
+
+// value returns the metric with the given name
+func (m *typeMetrics) value(name string) float64 {
+	switch name {
+	case metricFanIn:
+		return float64(m.fanIn)
+	case metricFanOut:
+		return float64(m.fanOut)
+	case metricMethods:
+		return float64(m.methods)
+	case metricFields:
+		return float64(m.fields)
+	case metricLines:
+		return float64(m.lines)
+	case metricComplexity:
+		return m.complexity
+	}
+	return 0
+}
+
+// exceeds reports whether any metric is above its threshold
+func (m *typeMetrics) exceeds(thresholds map[string]float64) bool {
+	for name, threshold := range thresholds {
+		if m.value(name) > threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// compartment returns the body lines showing the metrics, starting with a separator
+func (m *typeMetrics) compartment() []string {
+	return []string{
+		metricsSeparator,
+		fmt.Sprintf("%s: %d, %s: %d", metricFanIn, m.fanIn, metricFanOut, m.fanOut),
+		fmt.Sprintf("%s: %d, %s: %d", metricMethods, m.methods, metricFields, m.fields),
+		fmt.Sprintf("%s: %d, %s: %.1f", metricLines, m.lines, metricComplexity, m.complexity),
+	}
+}
+
+// parseMetricThresholds parses a list like "fan-in=10,complexity=5" into thresholds by metric name
+func parseMetricThresholds(list string) (map[string]float64, error) {
+	result := map[string]float64{}
+	for _, entry := range splitList(list) {
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !containsString(metricNames, name) {
+			return nil, fmt.Errorf(
+				"invalid metric threshold %q, expected one of %s followed by =value",
+				entry,
+				strings.Join(metricNames, ", "),
+			)
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric threshold %q: %w", entry, err)
+		}
+		result[name] = threshold
+	}
+	return result, nil
+}
+
+// dependency returns the names of the depending and the depended on class of a relationship. Arrows pointing to
+// the left, like <|-- and <.., and compositions, which goplantuml draws from the embedded type to the embedding one,
+// point from the right class to the left one.
+func dependency(e *Edge) (string, string) {
+	if strings.HasPrefix(e.Arrow, "<") || strings.HasPrefix(e.Arrow, "*") {
+		return e.To, e.From
+	}
+	return e.From, e.To
+}
+
+// cyclomaticComplexity returns 1 plus the number of decision points of a function body: conditions, loops,
+// non-default cases and boolean operators
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	if body == nil {
+		return complexity
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// lineCount returns the number of lines a node spans
+func lineCount(fset *token.FileSet, node ast.Node) int {
+	return fset.Position(node.End()).Line - fset.Position(node.Pos()).Line + 1
+}
+
+// computeMetrics returns the metrics of every class of the diagram declared in the sources. Fan-in and fan-out count
+// the distinct classes connected by relationships of all, the diagram of all relationships, so they do not depend on
+// the relationships the diagram draws.
+func computeMetrics(d, all *Diagram, idx *sourceIndex) []*typeMetrics {
+	classes := map[string]string{} // full names of the classes of all by full name and by reference
+	for _, c := range all.AllClasses() {
+		classes[c.FullName()] = c.FullName()
+		classes[c.Ref()] = c.FullName()
+	}
+	dependents := map[string]map[string]bool{}
+	dependees := map[string]map[string]bool{}
+	for _, e := range all.Edges {
+		fromName, toName := dependency(e)
+		from, to := classes[fromName], classes[toName]
+		if from == "" || to == "" || from == to {
+			continue
+		}
+		if dependees[from] == nil {
+			dependees[from] = map[string]bool{}
+		}
+		if dependents[to] == nil {
+			dependents[to] = map[string]bool{}
+		}
+		dependees[from][to] = true
+		dependents[to][from] = true
+	}
+
+	result := []*typeMetrics{}
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		m := &typeMetrics{
+			class:   c,
+			fanIn:   len(dependents[c.SourceName()]),
+			fanOut:  len(dependees[c.SourceName()]),
+			methods: len(t.methods),
+			lines:   lineCount(idx.fset, t.spec),
+		}
+		switch spec := t.spec.Type.(type) {
+		case *ast.StructType:
+			for _, field := range spec.Fields.List {
+				m.fields += max(len(field.Names), 1)
+			}
+		case *ast.InterfaceType:
+			for _, method := range spec.Methods.List {
+				if _, ok := method.Type.(*ast.FuncType); ok {
+					m.methods += len(method.Names)
+				}
+			}
+		}
+		total := 0
+		for _, method := range t.methods {
+			m.lines += lineCount(idx.fset, method.decl)
+			total += cyclomaticComplexity(method.decl.Body)
+		}
+		if len(t.methods) > 0 {
+			m.complexity = float64(total) / float64(len(t.methods))
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// addMetrics shows the metrics of every class in a compartment if show is set, and colors the classes with a metric
+// above its threshold unless they already have a color
+func addMetrics(metrics []*typeMetrics, show bool, thresholds map[string]float64) {
+	for _, m := range metrics {
+		if show {
+			m.class.Members = append(m.class.Members, m.compartment()...)
+			m.class.Members = append(m.class.Members, "")
+		}
+		if m.exceeds(thresholds) && !hasColor(m.class) {
+			addColor(m.class, hotspotColor)
+		}
+	}
+}
+
+// writeMetricsCSV writes the metrics to a CSV file with one row per class
+func writeMetricsCSV(path string, metrics []*typeMetrics) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("could not write %s: %w", path, closeErr)
+		}
+	}()
+	w := csv.NewWriter(f)
+	if err := w.Write(append([]string{"type"}, metricNames...)); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	for _, m := range metrics {
+		row := []string{m.class.FullName()}
+		for _, name := range metricNames {
+			if name == metricComplexity {
+				row = append(row, strconv.FormatFloat(m.complexity, 'f', 1, 64))
+			} else {
+				row = append(row, strconv.Itoa(int(m.value(name))))
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	return nil
+}
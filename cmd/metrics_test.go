@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// measuredSource declares a struct with methods of different complexity and an interface it implements
+const measuredSource = `package store
+
+type Repository interface {
+	Find(id int) (*User, error)
+	Close() error
+}
+
+type User struct {
+	ID         int
+	First, Last string
+}
+
+type SQL struct {
+	Users []*User
+	Repository
+}
+
+func (s *SQL) Find(id int) (*User, error) {
+	for _, u := range s.Users {
+		if u.ID == id && id > 0 {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *SQL) Close() error {
+	switch {
+	case s.Users == nil:
+		return nil
+	default:
+		return nil
+	}
+}
+`
+
+func TestComputeMetrics(t *testing.T) {
+	root := writeFiles(t, map[string]string{"store/store.go": measuredSource})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	ro := rulesRenderingOptions(map[goplantuml.RenderingOption]any{})
+	_ = result.SetRenderingOptions(ro)
+	all := ParseDiagram(result.Render())
+	idx := loadSourceIndex(sources, dirs)
+	addRuleRelationships(all, nil, false, idx, ro)
+	metrics := computeMetrics(d, all, idx)
+
+	expected := map[string]typeMetrics{
+		"store.Repository": {fanIn: 1, fanOut: 1, methods: 2, lines: 4},
+		"store.User":       {fanIn: 2, fields: 3, lines: 4},
+		"store.SQL":        {fanOut: 2, methods: 2, fields: 2, lines: 20, complexity: 3},
+	}
+	if len(metrics) != len(expected) {
+		t.Fatalf("computeMetrics() = %d types, want %d", len(metrics), len(expected))
+	}
+	for _, m := range metrics {
+		want, ok := expected[m.class.FullName()]
+		if !ok {
+			t.Errorf("unexpected metrics of %s", m.class.FullName())
+			continue
+		}
+		want.class = m.class
+		if *m != want {
+			t.Errorf("metrics of %s = %+v, want %+v", m.class.FullName(), *m, want)
+		}
+	}
+}
+
+func TestParseMetricThresholds(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     string
+		expected map[string]float64
+		wantErr  bool
+	}{
+		{name: "empty", list: "", expected: map[string]float64{}},
+		{
+			name:     "thresholds",
+			list:     "fan-in=10, complexity = 2.5",
+			expected: map[string]float64{metricFanIn: 10, metricComplexity: 2.5},
+		},
+		{name: "unknown metric", list: "size=10", wantErr: true},
+		{name: "invalid value", list: "lines=many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMetricThresholds(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMetricThresholds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(result) != len(tt.expected) {
+				t.Errorf("parseMetricThresholds() = %v, want %v", result, tt.expected)
+			}
+			for name, threshold := range tt.expected {
+				if result[name] != threshold {
+					t.Errorf("parseMetricThresholds()[%s] = %v, want %v", name, result[name], threshold)
+				}
+			}
+		})
+	}
+}
+
+func TestDependency(t *testing.T) {
+	tests := []struct {
+		arrow string
+		from  string
+		to    string
+	}{
+		{arrow: "<|--", from: "B", to: "A"},
+		{arrow: "<|..", from: "B", to: "A"},
+		{arrow: "*--", from: "B", to: "A"},
+		{arrow: "o--", from: "A", to: "B"},
+		{arrow: "..>", from: "A", to: "B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arrow, func(t *testing.T) {
+			from, to := dependency(&Edge{From: "A", Arrow: tt.arrow, To: "B"})
+			if from != tt.from || to != tt.to {
+				t.Errorf("dependency() = %s, %s, want %s, %s", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestAddMetrics(t *testing.T) {
+	hot := &Class{Kind: "class", Name: "SQL", Namespace: "store", Members: []string{"+ Users []*User", ""}}
+	cold := &Class{Kind: "class", Name: "User", Namespace: "store", Extra: "#Pink"}
+	metrics := []*typeMetrics{
+		{class: hot, fanOut: 2, methods: 2, fields: 2, lines: 20, complexity: 3.5},
+		{class: cold, fanIn: 12, fields: 3, lines: 4},
+	}
+	addMetrics(metrics, true, map[string]float64{metricComplexity: 3, metricFanIn: 10})
+
+	expected := []string{
+		"+ Users []*User", "",
+		metricsSeparator, "fan-in: 0, fan-out: 2", "methods: 2, fields: 2", "lines: 20, complexity: 3.5", "",
+	}
+	if !equalStrings(hot.Members, expected) {
+		t.Errorf("Members = %q, want %q", hot.Members, expected)
+	}
+	if hot.Extra != hotspotColor {
+		t.Errorf("Extra = %q, want %q", hot.Extra, hotspotColor)
+	}
+	if cold.Extra != "#Pink" {
+		t.Errorf("Extra = %q, want the color of the directive", cold.Extra)
+	}
+
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	if err := writeMetricsCSV(path, metrics); err != nil {
+		t.Fatalf("writeMetricsCSV() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	rows := []string{
+		"type,fan-in,fan-out,methods,fields,lines,complexity",
+		"store.SQL,0,2,2,2,20,3.5",
+		"store.User,12,0,0,3,4,0.0",
+	}
+	if csv := strings.Split(strings.TrimSpace(string(data)), "\n"); !equalStrings(csv, rows) {
+		t.Errorf("writeMetricsCSV() = %q, want %q", csv, rows)
+	}
+	missing := filepath.Join(t.TempDir(), "missing", "metrics.csv")
+	if err := writeMetricsCSV(missing, metrics); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("writeMetricsCSV() error = %v, want an error naming %s", err, missing)
+	}
+}
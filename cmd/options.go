@@ -0,0 +1,384 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// options are the command line flags of go2uml
+type options struct {
+	// input
+	recursive        bool
+	ignore           string
+	maxDepth         int
+	includeGenerated bool
+	excludeMocks     bool
+	tags             string
+	goos             string
+	goarch           string
+	typecheck        bool
+	external         string
+	stdlibInterfaces bool
+
+	// rendering
+	showAggregations        bool
+	hideFields              bool
+	hideMethods             bool
+	hideConnections         bool
+	showCompositions        bool
+	showImplementations     bool
+	showAliases             bool
+	showConnectionLabels    bool
+	title                   string
+	notes                   string
+	showOptionsAsNote       bool
+	aggregatePrivateMembers bool
+	hidePrivateMembers      bool
+	format                  string
+	output                  string
+	splitBy                 string
+	outputDir               string
+	maxNodes                int
+	maxEdges                int
+
+	// sources
+	showDocs                 bool
+	docSummary               bool
+	showDependencies         bool
+	interfaceStereotypes     string
+	hideInterfaceStereotypes bool
+	sortMembers              string
+	maxMembers               int
+	collapse                 string
+	collapseDepth            int
+	signature                string
+	qualify                  string
+	hideDeprecated           bool
+	showReceivers            bool
+	showTags                 *tagFilter
+
+	// analysis
+	showMetrics       bool
+	metricsThresholds string
+	metricsCSV        string
+	check             string
+	markViolations    bool
+	cycles            bool
+	highlightCycles   bool
+	changedSince      string
+	changedOnly       bool
+}
+
+// defineFlags defines the command line flags of go2uml on the flag set, which parses them into the returned options
+func defineFlags(flags *flag.FlagSet) *options {
+	o := &options{showTags: &tagFilter{}}
+	o.defineInputFlags(flags)
+	o.defineRenderingFlags(flags)
+	o.defineSourceFlags(flags)
+	o.defineAnalysisFlags(flags)
+	return o
+}
+
+// defineInputFlags defines the flags that select the packages and files to draw
+func (o *options) defineInputFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&o.recursive, "recursive", false, "walk all directories recursively")
+	flags.StringVar(
+		&o.ignore,
+		"ignore",
+		"",
+		"comma separated list of folders or gitignore-style patterns (e.g. **/testdata) to ignore",
+	)
+	flags.IntVar(&o.maxDepth, "max-depth", 0, "maximum nesting depth for packages (0 = unlimited)")
+	flags.BoolVar(
+		&o.includeGenerated,
+		"include-generated",
+		false,
+		"include files with a \"Code generated ... DO NOT EDIT.\" header, which are skipped by default",
+	)
+	flags.BoolVar(&o.excludeMocks, "exclude-mocks", false, "skip mocks generated by mockgen or mockery")
+	flags.StringVar(&o.tags, "tags", "", "comma separated list of build tags to satisfy when selecting files")
+	flags.StringVar(&o.goos, "goos", "", "GOOS to select files for (defaults to the one of the go tool)")
+	flags.StringVar(&o.goarch, "goarch", "", "GOARCH to select files for (defaults to the one of the go tool)")
+	flags.BoolVar(
+		&o.typecheck,
+		"typecheck",
+		false,
+		"detect implementations and embedded types from the type-checked packages instead of matching method names",
+	)
+	flags.StringVar(
+		&o.external,
+		"external-interfaces",
+		"",
+		"comma separated list of interfaces outside the diagram to draw implementations of (e.g. io.Reader,error)",
+	)
+	flags.BoolVar(
+		&o.stdlibInterfaces,
+		"stdlib-interfaces",
+		false,
+		"draw implementations of common standard library interfaces such as error, fmt.Stringer and io.Reader",
+	)
+}
+
+// defineRenderingFlags defines the flags that are passed on to goplantuml and the ones that select the output
+func (o *options) defineRenderingFlags(flags *flag.FlagSet) {
+	flags.BoolVar(
+		&o.showAggregations,
+		"show-aggregations",
+		false,
+		"renders public aggregations even when -hide-connections is used (do not render by default)",
+	)
+	flags.BoolVar(&o.hideFields, "hide-fields", false, "hides fields")
+	flags.BoolVar(&o.hideMethods, "hide-methods", false, "hides methods")
+	flags.BoolVar(&o.hideConnections, "hide-connections", false, "hides all connections in the diagram")
+	flags.BoolVar(
+		&o.showCompositions,
+		"show-compositions",
+		false,
+		"Shows compositions even when -hide-connections is used",
+	)
+	flags.BoolVar(
+		&o.showImplementations,
+		"show-implementations",
+		false,
+		"Shows implementations even when -hide-connections is used",
+	)
+	flags.BoolVar(&o.showAliases, "show-aliases", false, "Shows aliases even when -hide-connections is used")
+	flags.BoolVar(
+		&o.showConnectionLabels,
+		"show-connection-labels",
+		false,
+		"Shows labels in the connections to identify the connections types (e.g. extends, implements, aggregates, alias of",
+	)
+	flags.StringVar(&o.title, "title", "", "Title of the generated diagram")
+	flags.StringVar(&o.notes, "notes", "", "Comma separated list of notes to be added to the diagram")
+	flags.BoolVar(
+		&o.showOptionsAsNote,
+		"show-options-as-note",
+		false,
+		"Show a note in the diagram with the none evident options ran with this CLI",
+	)
+	flags.BoolVar(
+		&o.aggregatePrivateMembers,
+		"aggregate-private-members",
+		false,
+		"Show aggregations for private members. Ignored if -show-aggregations is not used.",
+	)
+	flags.BoolVar(&o.hidePrivateMembers, "hide-private-members", false, "Hide private fields and methods")
+	flags.StringVar(
+		&o.format,
+		"format",
+		"plantuml",
+		"output format: plantuml or mermaid (mermaid support is experimental)",
+	)
+	flags.StringVar(
+		&o.output,
+		"output",
+		"",
+		"output file path. If omitted, then this will default to standard output",
+	)
+	flags.StringVar(
+		&o.splitBy,
+		"split",
+		"",
+		"write one diagram per package and an index diagram to -output-dir: package",
+	)
+	flags.StringVar(&o.outputDir, "output-dir", "", "directory -split writes its diagrams to")
+	flags.IntVar(
+		&o.maxNodes,
+		"max-nodes",
+		0,
+		"split diagrams with more types than this into several parts (0 = unlimited)",
+	)
+	flags.IntVar(
+		&o.maxEdges,
+		"max-edges",
+		0,
+		"split diagrams with more relationships than this into several parts (0 = unlimited)",
+	)
+}
+
+// defineSourceFlags defines the flags that add what goplantuml does not draw from the sources or change the members
+// and packages it draws
+func (o *options) defineSourceFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&o.showDocs, "show-docs", false, "attach the doc comment of every type as a note and a tooltip")
+	flags.BoolVar(
+		&o.docSummary,
+		"doc-summary",
+		false,
+		"shorten doc comments shown with -show-docs to their first sentence",
+	)
+	flags.BoolVar(
+		&o.showDependencies,
+		"show-dependencies",
+		false,
+		"draw dashed edges to the types used in method parameters and results but not in fields",
+	)
+	flags.StringVar(
+		&o.interfaceStereotypes,
+		"interface-stereotypes",
+		"",
+		"comma separated Stereotype=Interface mappings for interfaces of the diagram (e.g. Repository=store.Repository)",
+	)
+	flags.BoolVar(
+		&o.hideInterfaceStereotypes,
+		"hide-interface-stereotypes",
+		false,
+		"do not add stereotypes like <<error>> or <<Stringer>> to implementations of well-known interfaces",
+	)
+	flags.StringVar(
+		&o.sortMembers,
+		"sort-members",
+		"",
+		"order of fields and methods: source, alpha or visibility (defaults to the order goplantuml emits)",
+	)
+	flags.IntVar(
+		&o.maxMembers,
+		"max-members",
+		0,
+		"maximum number of fields and methods shown per type, the others are counted (0 = unlimited)",
+	)
+	flags.StringVar(
+		&o.collapse,
+		"collapse",
+		"",
+		"comma separated list of packages drawn as a single node with their type count",
+	)
+	flags.IntVar(
+		&o.collapseDepth,
+		"collapse-depth",
+		0,
+		"draw packages nested deeper than this as a single node with their type count (0 = none)",
+	)
+	flags.StringVar(
+		&o.signature,
+		"signature",
+		signatureFull,
+		"how much of fields and methods is shown: full, types (no parameter names), names (no types) or none",
+	)
+	flags.StringVar(
+		&o.qualify,
+		"qualify",
+		"",
+		"how type names of members are qualified: none, package or path (defaults to how they are written)",
+	)
+	flags.BoolVar(
+		&o.hideDeprecated,
+		"hide-deprecated",
+		false,
+		"leave out types, fields and methods marked as Deprecated",
+	)
+	flags.BoolVar(&o.showReceivers, "show-receivers", false, "mark methods with pointer or value receivers")
+	flags.Var(
+		o.showTags,
+		"show-tags",
+		"show struct tags on fields, optionally only the given keys (e.g. -show-tags=json,db)",
+	)
+}
+
+// defineAnalysisFlags defines the flags of metrics, architecture rules, cycles and changes
+func (o *options) defineAnalysisFlags(flags *flag.FlagSet) {
+	flags.BoolVar(
+		&o.showMetrics,
+		"metrics",
+		false,
+		"show fan-in, fan-out, methods, fields, lines and average cyclomatic complexity of every type",
+	)
+	flags.StringVar(
+		&o.metricsThresholds,
+		"metrics-thresholds",
+		"",
+		"color types with a metric above its threshold, e.g. -metrics-thresholds=fan-in=10,complexity=5",
+	)
+	flags.StringVar(&o.metricsCSV, "metrics-csv", "", "write the metrics of every type to this CSV file")
+	flags.StringVar(
+		&o.check,
+		"check",
+		"",
+		"check the architecture rules of this file, print their violations and exit with 1 if there are any",
+	)
+	flags.BoolVar(
+		&o.markViolations,
+		"mark-violations",
+		false,
+		"render the diagram with the relationships violating -check rules in red",
+	)
+	flags.BoolVar(&o.cycles, "cycles", false, "print the cycles between types and between packages")
+	flags.BoolVar(
+		&o.highlightCycles,
+		"highlight-cycles",
+		false,
+		"print the cycles between types and between packages and draw their types and relationships in red",
+	)
+	flags.StringVar(
+		&o.changedSince,
+		"changed-since",
+		"",
+		"color the types and methods changed since this git revision, uncommitted changes included",
+	)
+	flags.BoolVar(
+		&o.changedOnly,
+		"changed-only",
+		false,
+		"draw only the types changed since -changed-since and the types directly related to them",
+	)
+}
+
+// validate checks the values of the flags that take one of a set of values or depend on other flags
+func (o *options) validate() error {
+	switch {
+	case o.changedOnly && o.changedSince == "":
+		return errors.New("-changed-only requires -changed-since")
+	case o.sortMembers != "" && !containsString(memberOrders, o.sortMembers):
+		return fmt.Errorf("-sort-members must be one of %s", strings.Join(memberOrders, ", "))
+	case !containsString(signatureModes, o.signature):
+		return fmt.Errorf("-signature must be one of %s", strings.Join(signatureModes, ", "))
+	case o.qualify != "" && !containsString(qualifyModes, o.qualify):
+		return fmt.Errorf("-qualify must be one of %s", strings.Join(qualifyModes, ", "))
+	case o.splitBy != "" && o.splitBy != splitPackage:
+		return fmt.Errorf("-split must be %s", splitPackage)
+	case o.splitBy != "" && (o.outputDir == "" || o.output != ""):
+		return errors.New("-split writes to -output-dir, which is required, and cannot be used with -output")
+	}
+	return nil
+}
+
+// renderingOptions returns the goplantuml rendering options of the flags, with the legend of -show-options-as-note
+// and the -notes
+func (o *options) renderingOptions() (map[goplantuml.RenderingOption]any, error) {
+	ro := map[goplantuml.RenderingOption]any{
+		goplantuml.RenderConnectionLabels:  o.showConnectionLabels,
+		goplantuml.RenderFields:            !o.hideFields,
+		goplantuml.RenderMethods:           !o.hideMethods,
+		goplantuml.RenderAggregations:      o.showAggregations,
+		goplantuml.RenderTitle:             o.title,
+		goplantuml.AggregatePrivateMembers: o.aggregatePrivateMembers,
+		goplantuml.RenderPrivateMembers:    !o.hidePrivateMembers,
+	}
+	if o.hideConnections {
+		ro[goplantuml.RenderAliases] = o.showAliases
+		ro[goplantuml.RenderCompositions] = o.showCompositions
+		ro[goplantuml.RenderImplementations] = o.showImplementations
+	}
+	noteList := []string{}
+	if o.showOptionsAsNote {
+		legend, err := getLegend(ro)
+		if err != nil {
+			return nil, err
+		}
+		noteList = append(noteList, legend)
+	}
+	if o.notes != "" {
+		noteList = append(noteList, "", "<b><u>Notes</u></b>")
+	}
+	for _, note := range strings.Split(o.notes, ",") {
+		if trimmed := strings.TrimSpace(note); trimmed != "" {
+			noteList = append(noteList, trimmed)
+		}
+	}
+	ro[goplantuml.RenderNotes] = strings.Join(noteList, "\n")
+	return ro, nil
+}
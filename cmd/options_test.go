@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// parseOptions parses command line arguments into options
+func parseOptions(t *testing.T, args ...string) *options {
+	t.Helper()
+	flags := flag.NewFlagSet("go2uml", flag.ContinueOnError)
+	o := defineFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return o
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "defaults", args: []string{}},
+		{name: "changed only", args: []string{"-changed-only"}, expected: "-changed-only requires -changed-since"},
+		{name: "changes", args: []string{"-changed-since=main", "-changed-only"}},
+		{name: "sort members", args: []string{"-sort-members=size"}, expected: "-sort-members must be one of"},
+		{name: "signature", args: []string{"-signature=short"}, expected: "-signature must be one of"},
+		{name: "qualify", args: []string{"-qualify=full"}, expected: "-qualify must be one of"},
+		{name: "split", args: []string{"-split=type", "-output-dir=out"}, expected: "-split must be package"},
+		{name: "split without directory", args: []string{"-split=package"}, expected: "-split writes to -output-dir"},
+		{name: "split to directory", args: []string{"-split=package", "-output-dir=out"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseOptions(t, tt.args...).validate()
+			if tt.expected == "" && err != nil {
+				t.Errorf("validate() error = %v, want none", err)
+			}
+			if tt.expected != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.expected)) {
+				t.Errorf("validate() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOptionsRenderingOptions(t *testing.T) {
+	o := parseOptions(t, "-hide-connections", "-show-aliases", "-hide-fields", "-notes=first, second")
+	ro, err := o.renderingOptions()
+	if err != nil {
+		t.Fatalf("renderingOptions() error = %v", err)
+	}
+	expected := map[goplantuml.RenderingOption]any{
+		goplantuml.RenderAliases:         true,
+		goplantuml.RenderCompositions:    false,
+		goplantuml.RenderImplementations: false,
+		goplantuml.RenderFields:          false,
+		goplantuml.RenderMethods:         true,
+		goplantuml.RenderNotes:           "\n<b><u>Notes</u></b>\nfirst\nsecond",
+	}
+	for option, want := range expected {
+		if ro[option] != want {
+			t.Errorf("option %v = %v, want %v", option, ro[option], want)
+		}
+	}
+	if ro, _ := parseOptions(t).renderingOptions(); ro[goplantuml.RenderAliases] != nil {
+		t.Errorf("RenderAliases = %v, want goplantuml's default", ro[goplantuml.RenderAliases])
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// recursiveSuffix is the wildcard suffix of go tool patterns such as ./... or example.com/mod/internal/...
+const recursiveSuffix = "/..."
+
+// packageSelection lists the directories to parse, resolved from the positional arguments
+type packageSelection struct {
+	dirs      []string // absolute directories handed to goplantuml
+	recursive bool     // whether goplantuml walks the directories
+	ignored   []string // directories excluded from the walk, e.g. nested modules
+}
+
+// module is the Go module that contains the working directory
+type module struct {
+	root string
+	path string
+}
+
+// findModule looks for the go.mod file in dir or one of its parents
+func findModule(dir string) (*module, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		modFile := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(modFile); err == nil {
+			modulePath, err := readModulePath(modFile)
+			if err != nil {
+				return nil, err
+			}
+			return &module{root: dir, path: modulePath}, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, errors.New("go.mod file not found in the working directory or any parent directory")
+		}
+		dir = parent
+	}
+}
+
+// readModulePath returns the path of the module directive in a go.mod file
+func readModulePath(modFile string) (string, error) {
+	file, err := os.Open(modFile) // #nosec G304 -- go.mod is looked up from the working directory
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", modFile, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted, nil
+		}
+		return fields[1], nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("could not read %s: %w", modFile, err)
+	}
+	return "", fmt.Errorf("no module directive in %s", modFile)
+}
+
+// importPath returns the import path of the package in a directory of the module
+func (m *module) importPath(dir string) (string, error) {
+	rel, err := filepath.Rel(m.root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("directory %s is not in the module %s", dir, m.path)
+	}
+	return path.Join(m.path, filepath.ToSlash(rel)), nil
+}
+
+// dir resolves an import path of the module to its directory. Packages of nested modules are not part of the
+// module, the same way the go tool treats them.
+func (m *module) dir(importPath string) (string, error) {
+	if importPath != m.path && !strings.HasPrefix(importPath, m.path+"/") {
+		return "", fmt.Errorf("package %s is not in the main module %s", importPath, m.path)
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, m.path), "/")
+	dir := filepath.Join(m.root, filepath.FromSlash(rel))
+	fi, err := os.Stat(dir)
+	if err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("could not find package %s in %s", importPath, m.root)
+	}
+	for parent := dir; parent != m.root; parent = filepath.Dir(parent) {
+		if _, err := os.Stat(filepath.Join(parent, "go.mod")); err == nil {
+			return "", fmt.Errorf("package %s is in the nested module at %s", importPath, parent)
+		}
+	}
+	return dir, nil
+}
+
+// isFilePathPattern reports whether a pattern is a file system path rather than an import path
+func isFilePathPattern(pattern string) bool {
+	return pattern == "." || pattern == ".." || filepath.IsAbs(pattern) ||
+		strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../") ||
+		strings.HasPrefix(pattern, `.\`) || strings.HasPrefix(pattern, `..\`)
+}
+
+// excludedByGoTool returns the directories below root that the go tool does not match with root/...:
+// nested modules, testdata directories and directories starting with an underscore.
+// Directories starting with a dot and vendor directories are already skipped by goplantuml.
+func excludedByGoTool(root string) ([]string, error) {
+	result := []string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == root {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, ".") || name == "vendor" {
+			return filepath.SkipDir
+		}
+		if name == "testdata" || strings.HasPrefix(name, "_") {
+			result = append(result, p)
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+			result = append(result, p)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk directory %s: %w", root, err)
+	}
+	return result, nil
+}
+
+// isWithin reports whether dir is root or one of its subdirectories
+func isWithin(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// getDirectories resolves the positional arguments into the directories to parse. An argument is either a
+// directory, a go tool pattern ending in /... or an import path of the module in the working directory.
+func getDirectories(args []string, recursive bool) (*packageSelection, error) {
+	if len(args) < 1 {
+		return nil, errors.New("DIR missing")
+	}
+	var mod *module
+	resolveImportPath := func(importPath string) (string, error) {
+		if mod == nil {
+			var err error
+			if mod, err = findModule("."); err != nil {
+				return "", fmt.Errorf("could not resolve %s: %w", importPath, err)
+			}
+		}
+		return mod.dir(importPath)
+	}
+
+	plainDirs := []string{}
+	wildcardDirs := []string{}
+	for _, arg := range args {
+		pattern := filepath.ToSlash(arg)
+		wildcard := pattern == "..." || strings.HasSuffix(pattern, recursiveSuffix)
+		base := arg
+		if wildcard {
+			base = strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+			if base == "" {
+				base = "."
+			}
+		}
+		if strings.Contains(base, "...") {
+			return nil, fmt.Errorf("unsupported pattern %s, ... is only supported at the end", arg)
+		}
+
+		var dir string
+		fi, err := os.Stat(base)
+		switch {
+		case err == nil && fi.IsDir():
+			dir = base
+		case err == nil:
+			return nil, fmt.Errorf("%s is not a directory", base)
+		case isFilePathPattern(base):
+			return nil, fmt.Errorf("could not find directory %s", base)
+		default:
+			if dir, err = resolveImportPath(base); err != nil {
+				return nil, err
+			}
+		}
+		dirAbs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not find directory %s", dir)
+		}
+		if wildcard {
+			wildcardDirs = append(wildcardDirs, dirAbs)
+		} else {
+			plainDirs = append(plainDirs, dirAbs)
+		}
+	}
+	return newPackageSelection(plainDirs, wildcardDirs, recursive)
+}
+
+// newPackageSelection combines plain directories and the roots of /... patterns into one goplantuml run.
+// goplantuml only has a single recursive switch, so when it has to walk the pattern roots, the subdirectories
+// of plain directories are excluded from the walk.
+func newPackageSelection(plainDirs, wildcardDirs []string, recursive bool) (*packageSelection, error) {
+	result := &packageSelection{recursive: recursive || len(wildcardDirs) > 0}
+	covered := func(dir string, roots []string) bool {
+		for _, root := range roots {
+			if dir != root && isWithin(dir, root) {
+				return true
+			}
+		}
+		return false
+	}
+	walked := wildcardDirs
+	if recursive {
+		walked = append(append([]string{}, wildcardDirs...), plainDirs...)
+	}
+
+	seen := map[string]struct{}{}
+	for _, dir := range wildcardDirs {
+		if _, ok := seen[dir]; ok || covered(dir, walked) {
+			continue
+		}
+		seen[dir] = struct{}{}
+		result.dirs = append(result.dirs, dir)
+		excluded, err := excludedByGoTool(dir)
+		if err != nil {
+			return nil, err
+		}
+		result.ignored = append(result.ignored, excluded...)
+	}
+	for _, dir := range plainDirs {
+		if _, ok := seen[dir]; ok || covered(dir, walked) {
+			continue
+		}
+		seen[dir] = struct{}{}
+		result.dirs = append(result.dirs, dir)
+		if recursive || len(wildcardDirs) == 0 {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not read directory %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				result.ignored = append(result.ignored, filepath.Join(dir, entry.Name()))
+			}
+		}
+		for _, root := range wildcardDirs {
+			if covered(root, []string{dir}) {
+				return nil, fmt.Errorf(
+					"%s is inside %s, use %s%s or -recursive to include both", root, dir, dir, recursiveSuffix)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a module example.com/app with a nested module in tools/ and returns its root
+func writeModule(t *testing.T) string {
+	t.Helper()
+	return writeFiles(t, map[string]string{
+		"go.mod":                     "module example.com/app\n\ngo 1.25\n",
+		"main.go":                    "package main\n",
+		"internal/api/api.go":        "package api\n",
+		"internal/api/v1/v1.go":      "package v1\n",
+		"internal/api/testdata/x.go": "package testdata\n",
+		"internal/_scratch/s.go":     "package scratch\n",
+		"tools/go.mod":               "module \"example.com/app/tools\"\n",
+		"tools/gen/gen.go":           "package gen\n",
+		"pkg/util/util.go":           "package util\n",
+		"pkg/util/inner/inner.go":    "package inner\n",
+	})
+}
+
+// writeFiles writes the files, given by slash separated paths, into a temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Resolve symlinks so paths compare equal to the ones of the working directory, e.g. /tmp on macOS
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestFindModule(t *testing.T) {
+	root := writeModule(t)
+
+	mod, err := findModule(filepath.Join(root, "internal", "api"))
+	if err != nil {
+		t.Fatalf("findModule() error = %v", err)
+	}
+	if mod.root != root || mod.path != "example.com/app" {
+		t.Errorf("findModule() = %+v, want root %s and path example.com/app", mod, root)
+	}
+
+	nested, err := findModule(filepath.Join(root, "tools", "gen"))
+	if err != nil {
+		t.Fatalf("findModule() error = %v", err)
+	}
+	if nested.path != "example.com/app/tools" {
+		t.Errorf("findModule() path = %s, want example.com/app/tools", nested.path)
+	}
+}
+
+func TestModuleDir(t *testing.T) {
+	root := writeModule(t)
+	mod := &module{root: root, path: "example.com/app"}
+
+	tests := []struct {
+		name       string
+		importPath string
+		expected   string
+		errPart    string
+	}{
+		{
+			name:       "module root",
+			importPath: "example.com/app",
+			expected:   root,
+		},
+		{
+			name:       "package in module",
+			importPath: "example.com/app/internal/api",
+			expected:   filepath.Join(root, "internal", "api"),
+		},
+		{
+			name:       "other module",
+			importPath: "github.com/other/lib",
+			errPart:    "not in the main module",
+		},
+		{
+			name:       "module path prefix of another path",
+			importPath: "example.com/application",
+			errPart:    "not in the main module",
+		},
+		{
+			name:       "package of nested module",
+			importPath: "example.com/app/tools/gen",
+			errPart:    "nested module",
+		},
+		{
+			name:       "missing package",
+			importPath: "example.com/app/missing",
+			errPart:    "could not find package",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mod.dir(tt.importPath)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("dir() error = %v, want error containing %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dir() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("dir() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetDirectories(t *testing.T) {
+	root := writeModule(t)
+	t.Chdir(root)
+	join := func(parts ...string) string {
+		return filepath.Join(append([]string{root}, parts...)...)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		recursive bool
+		dirs      []string
+		ignored   []string
+		walk      bool
+	}{
+		{
+			name: "plain directory",
+			args: []string{"./internal/api"},
+			dirs: []string{join("internal", "api")},
+		},
+		{
+			name:      "plain directory with -recursive",
+			args:      []string{"pkg/util"},
+			recursive: true,
+			dirs:      []string{join("pkg", "util")},
+			walk:      true,
+		},
+		{
+			name:    "all packages of the module",
+			args:    []string{"./..."},
+			dirs:    []string{root},
+			ignored: []string{join("internal", "_scratch"), join("internal", "api", "testdata"), join("tools")},
+			walk:    true,
+		},
+		{
+			name:    "import path pattern",
+			args:    []string{"example.com/app/internal/..."},
+			dirs:    []string{join("internal")},
+			ignored: []string{join("internal", "_scratch"), join("internal", "api", "testdata")},
+			walk:    true,
+		},
+		{
+			name: "import path",
+			args: []string{"example.com/app/pkg/util"},
+			dirs: []string{join("pkg", "util")},
+		},
+		{
+			name:    "pattern combined with a plain directory",
+			args:    []string{"./internal/...", "./pkg/util"},
+			dirs:    []string{join("internal"), join("pkg", "util")},
+			ignored: []string{join("internal", "_scratch"), join("internal", "api", "testdata"), join("pkg", "util", "inner")},
+			walk:    true,
+		},
+		{
+			name: "directory inside a pattern is not parsed twice",
+			args: []string{"./...", "./internal/api", "./internal/..."},
+			dirs: []string{root},
+			ignored: []string{
+				join("internal", "_scratch"),
+				join("internal", "api", "testdata"),
+				join("tools"),
+			},
+			walk: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := getDirectories(tt.args, tt.recursive)
+			if err != nil {
+				t.Fatalf("getDirectories() error = %v", err)
+			}
+			if !equalStrings(result.dirs, tt.dirs) {
+				t.Errorf("getDirectories() dirs = %v, want %v", result.dirs, tt.dirs)
+			}
+			if !equalStrings(result.ignored, tt.ignored) {
+				t.Errorf("getDirectories() ignored = %v, want %v", result.ignored, tt.ignored)
+			}
+			if result.recursive != tt.walk {
+				t.Errorf("getDirectories() recursive = %v, want %v", result.recursive, tt.walk)
+			}
+		})
+	}
+}
+
+func TestGetDirectoriesErrors(t *testing.T) {
+	root := writeModule(t)
+	t.Chdir(root)
+
+	tests := []struct {
+		name    string
+		args    []string
+		errPart string
+	}{
+		{
+			name:    "no arguments",
+			args:    nil,
+			errPart: "DIR missing",
+		},
+		{
+			name:    "missing directory",
+			args:    []string{"./missing"},
+			errPart: "could not find directory",
+		},
+		{
+			name:    "file instead of directory",
+			args:    []string{"main.go"},
+			errPart: "is not a directory",
+		},
+		{
+			name:    "wildcard in the middle",
+			args:    []string{"./internal/.../v1"},
+			errPart: "unsupported pattern",
+		},
+		{
+			name:    "import path outside of the module",
+			args:    []string{"github.com/other/lib/..."},
+			errPart: "not in the main module",
+		},
+		{
+			name:    "pattern inside a plain directory",
+			args:    []string{"./internal", "./internal/api/..."},
+			errPart: "is inside",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getDirectories(tt.args, false)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("getDirectories() error = %v, want error containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+// equalStrings compares two string slices ignoring their order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
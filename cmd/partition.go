@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// partNotePosition is the side of its class the note listing related classes of other parts is drawn on
+const partNotePosition = "bottom"
+
+// sizeLimits are the most classes and relationships a diagram may have, 0 for no limit
+type sizeLimits struct {
+	nodes int
+	edges int
+}
+
+// enabled reports whether any limit is set
+func (l sizeLimits) enabled() bool {
+	return l.nodes > 0 || l.edges > 0
+}
+
+// fits reports whether a diagram with the given number of classes and relationships is within the limits
+func (l sizeLimits) fits(nodes, edges int) bool {
+	return (l.nodes <= 0 || nodes <= l.nodes) && (l.edges <= 0 || edges <= l.edges)
+}
+
+// exceeding returns the numbers, starting at 1, of the parts that are not within the limits, e.g. a class with more
+// relationships than the limit allows
+func (l sizeLimits) exceeding(parts []*Diagram) []int {
+	result := []int{}
+	for i, part := range parts {
+		if !l.fits(len(part.AllClasses()), len(part.Edges)) {
+			result = append(result, i+1)
+		}
+	}
+	return result
+}
+
+// partitioner assigns the classes of a diagram to parts that are within the size limits
+type partitioner struct {
+	d       *Diagram
+	limits  sizeLimits
+	classes map[string]*Class // classes by full name and by reference
+}
+
+// class returns the class a relationship end refers to, or nil for types that are not part of the diagram
+func (p *partitioner) class(name string) *Class {
+	return p.classes[name]
+}
+
+// edges returns the number of relationships between classes of the set, including those with an end that is not
+// part of the diagram
+func (p *partitioner) edges(set map[*Class]bool) int {
+	count := 0
+	for _, e := range p.d.Edges {
+		from, to := p.class(e.From), p.class(e.To)
+		if (from == nil || set[from]) && (to == nil || set[to]) && (from != nil || to != nil) {
+			count++
+		}
+	}
+	return count
+}
+
+// fits reports whether the classes of the set and their relationships are within the limits
+func (p *partitioner) fits(set map[*Class]bool) bool {
+	return p.limits.fits(len(set), p.edges(set))
+}
+
+// components returns the connected components of the classes, in the order of their first class
+func (p *partitioner) components(classes []*Class) [][]*Class {
+	parent := map[*Class]*Class{}
+	var find func(c *Class) *Class
+	find = func(c *Class) *Class {
+		if parent[c] == c {
+			return c
+		}
+		parent[c] = find(parent[c])
+		return parent[c]
+	}
+	for _, c := range classes {
+		parent[c] = c
+	}
+	for _, e := range p.d.Edges {
+		from, to := p.class(e.From), p.class(e.To)
+		if from != nil && to != nil && parent[from] != nil && parent[to] != nil {
+			parent[find(from)] = find(to)
+		}
+	}
+	result := [][]*Class{}
+	index := map[*Class]int{}
+	for _, c := range classes {
+		root := find(c)
+		i, ok := index[root]
+		if !ok {
+			i = len(result)
+			index[root] = i
+			result = append(result, nil)
+		}
+		result[i] = append(result[i], c)
+	}
+	return result
+}
+
+// units splits classes into groups within the limits: connected components first, components that are too large by
+// namespace, and namespaces that are still too large into single classes
+func (p *partitioner) units(classes []*Class) [][]*Class {
+	result := [][]*Class{}
+	for _, component := range p.components(classes) {
+		if p.fits(toSet(component)) {
+			result = append(result, component)
+			continue
+		}
+		clusters := [][]*Class{}
+		index := map[string]int{}
+		for _, c := range component {
+			i, ok := index[c.Namespace]
+			if !ok {
+				i = len(clusters)
+				index[c.Namespace] = i
+				clusters = append(clusters, nil)
+			}
+			clusters[i] = append(clusters[i], c)
+		}
+		for _, cluster := range clusters {
+			if len(clusters) > 1 && p.fits(toSet(cluster)) {
+				result = append(result, cluster)
+				continue
+			}
+			for _, c := range cluster {
+				result = append(result, []*Class{c})
+			}
+		}
+	}
+	return result
+}
+
+// toSet returns the classes as a set
+func toSet(classes []*Class) map[*Class]bool {
+	set := map[*Class]bool{}
+	for _, c := range classes {
+		set[c] = true
+	}
+	return set
+}
+
+// partitionDiagram splits a diagram that exceeds the limits into diagrams within them. Connected classes are kept
+// together where possible, otherwise the classes of a namespace. Relationships between classes of different parts
+// are left out, a note on each of their classes lists the related classes and the part they are drawn in. Parts
+// that still exceed the limits are reported with a warning.
+func partitionDiagram(d *Diagram, limits sizeLimits) []*Diagram {
+	if !limits.enabled() {
+		return []*Diagram{d}
+	}
+	result := partition(d, limits)
+	for _, part := range limits.exceeding(result) {
+		slog.Warn("part of the diagram exceeds -max-nodes or -max-edges", "part", part, "parts", len(result))
+	}
+	return result
+}
+
+// partition splits a diagram into parts within the limits where possible, see partitionDiagram
+func partition(d *Diagram, limits sizeLimits) []*Diagram {
+	all := d.AllClasses()
+	if limits.fits(len(all), len(d.Edges)) {
+		return []*Diagram{d}
+	}
+	p := &partitioner{d: d, limits: limits, classes: map[string]*Class{}}
+	for _, c := range all {
+		p.classes[c.FullName()] = c
+		p.classes[c.Ref()] = c
+	}
+
+	parts := []map[*Class]bool{}
+	for _, unit := range p.units(all) {
+		if len(parts) > 0 {
+			last := parts[len(parts)-1]
+			merged := toSet(unit)
+			for c := range last {
+				merged[c] = true
+			}
+			if p.fits(merged) {
+				parts[len(parts)-1] = merged
+				continue
+			}
+		}
+		parts = append(parts, toSet(unit))
+	}
+	if len(parts) == 1 {
+		return []*Diagram{d}
+	}
+	partOf := map[*Class]int{}
+	for i, part := range parts {
+		for c := range part {
+			partOf[c] = i
+		}
+	}
+
+	groups := map[string]bool{}
+	var walk func(namespaces []*Namespace)
+	walk = func(namespaces []*Namespace) {
+		for _, ns := range namespaces {
+			groups[ns.Path] = ns.Group
+			walk(ns.Children)
+		}
+	}
+	walk(d.Namespaces)
+
+	result := []*Diagram{}
+	for i := range parts {
+		pd := &Diagram{Header: append([]string{}, d.Header...), Footer: append([]string{}, d.Footer...)}
+		for _, c := range all {
+			if partOf[c] == i {
+				pd.AddClass(c, groups[c.Namespace])
+			}
+		}
+		result = append(result, pd)
+	}
+	related := map[*Class][]string{}
+	for _, e := range d.Edges {
+		from, to := p.class(e.From), p.class(e.To)
+		switch {
+		case from != nil && to != nil && partOf[from] != partOf[to]:
+			related[from] = append(related[from], fmt.Sprintf("%s (part %d)", to.FullName(), partOf[to]+1))
+			related[to] = append(related[to], fmt.Sprintf("%s (part %d)", from.FullName(), partOf[from]+1))
+		case from != nil:
+			result[partOf[from]].Edges = append(result[partOf[from]].Edges, e)
+		case to != nil:
+			result[partOf[to]].Edges = append(result[partOf[to]].Edges, e)
+		}
+	}
+	for _, n := range d.Notes {
+		class, _, _ := strings.Cut(n.Target, "::")
+		if c := p.class(class); c != nil {
+			result[partOf[c]].Notes = append(result[partOf[c]].Notes, n)
+		}
+	}
+	for _, c := range all {
+		if len(related[c]) == 0 {
+			continue
+		}
+		lines := []string{}
+		for _, line := range related[c] {
+			if !containsString(lines, line) {
+				lines = append(lines, line)
+			}
+		}
+		sort.Strings(lines)
+		result[partOf[c]].Notes = append(result[partOf[c]].Notes, &Note{
+			Position: partNotePosition,
+			Target:   c.Ref(),
+			Text:     "related in other parts:\n" + strings.Join(lines, "\n"),
+		})
+	}
+	return result
+}
+
+// renderParts renders the parts of a partitioned diagram in the format, one after another separated by a blank line.
+// Every part is a document of its own, in the same shape as an unpartitioned diagram.
+func renderParts(parts []*Diagram, format string) (string, error) {
+	result := []string{}
+	for _, part := range parts {
+		rendered, err := convertDiagram(part.Render(), format)
+		if err != nil {
+			return "", err
+		}
+		result = append(result, strings.TrimSuffix(rendered, "\n")+"\n")
+	}
+	return strings.Join(result, "\n"), nil
+}
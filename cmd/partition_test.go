@@ -0,0 +1,179 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// componentsDiagram has two connected components, one spanning two namespaces, and a class without relationships
+const componentsDiagram = `@startuml
+namespace app {
+    class "Handler" << (S,Aquamarine) >> {
+    }
+    class "Server" << (S,Aquamarine) >> {
+    }
+}
+namespace store {
+    class "User" << (S,Aquamarine) >> {
+    }
+    interface "Repository" {
+    }
+    class "SQL" << (S,Aquamarine) >> {
+    }
+}
+namespace log {
+    class "Logger" << (S,Aquamarine) >> {
+    }
+    class "Level" << (S,Aquamarine) >> {
+    }
+}
+
+"app.Server" o-- "app.Handler"
+"app.Handler" o-- "store.Repository"
+"store.Repository" <|-- "store.SQL"
+"store.SQL" o-- "store.User"
+"log.Logger" o-- "log.Level"
+"log.Logger" o-- "time.Time"
+@enduml
+`
+
+func TestPartitionDiagram(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits sizeLimits
+		parts  [][]string
+		notes  map[string]string
+	}{
+		{
+			name:   "within the limits",
+			limits: sizeLimits{nodes: 7, edges: 6},
+			parts: [][]string{
+				{"app.Handler", "app.Server", "store.User", "store.Repository", "store.SQL", "log.Logger", "log.Level"},
+			},
+		},
+		{
+			name:   "connected components",
+			limits: sizeLimits{nodes: 5},
+			parts: [][]string{
+				{"app.Handler", "app.Server", "store.User", "store.Repository", "store.SQL"},
+				{"log.Logger", "log.Level"},
+			},
+		},
+		{
+			name:   "namespaces",
+			limits: sizeLimits{nodes: 4, edges: 3},
+			parts: [][]string{
+				{"app.Handler", "app.Server"},
+				{"store.User", "store.Repository", "store.SQL"},
+				{"log.Logger", "log.Level"},
+			},
+			notes: map[string]string{
+				"app.Handler":      "related in other parts:\nstore.Repository (part 2)",
+				"store.Repository": "related in other parts:\napp.Handler (part 1)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := partitionDiagram(ParseDiagram(componentsDiagram), tt.limits)
+			if len(parts) != len(tt.parts) {
+				t.Fatalf("partitionDiagram() = %d parts, want %d", len(parts), len(tt.parts))
+			}
+			notes := map[string]string{}
+			for i, part := range parts {
+				names := []string{}
+				for _, c := range part.AllClasses() {
+					names = append(names, c.FullName())
+				}
+				if !equalStrings(names, tt.parts[i]) {
+					t.Errorf("part %d = %q, want %q", i+1, names, tt.parts[i])
+				}
+				if !tt.limits.fits(len(names), len(part.Edges)) {
+					t.Errorf("part %d has %d classes and %d relationships, over the limits", i+1, len(names), len(part.Edges))
+				}
+				for _, n := range part.Notes {
+					notes[n.Target] = n.Text
+				}
+			}
+			if len(notes) != len(tt.notes) {
+				t.Errorf("notes = %q, want %q", notes, tt.notes)
+			}
+			for target, text := range tt.notes {
+				if notes[target] != text {
+					t.Errorf("note of %s = %q, want %q", target, notes[target], text)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderParts(t *testing.T) {
+	parts := partitionDiagram(ParseDiagram(componentsDiagram), sizeLimits{nodes: 5})
+	tests := []struct {
+		format   string
+		expected int
+		marker   string
+	}{
+		{format: "plantuml", expected: 2, marker: "@startuml"},
+		{format: "mermaid", expected: 2, marker: "classDiagram"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			result, err := renderParts(parts, tt.format)
+			if err != nil {
+				t.Fatalf("renderParts() error = %v", err)
+			}
+			if count := strings.Count(result, tt.marker); count != tt.expected {
+				t.Errorf("renderParts() has %d diagrams, want %d:\n%s", count, tt.expected, result)
+			}
+			first, err := convertDiagram(parts[0].Render(), tt.format)
+			if err != nil {
+				t.Fatalf("convertDiagram() error = %v", err)
+			}
+			if !strings.HasPrefix(result, first+"\n") {
+				t.Errorf("renderParts() does not start with the first part as a diagram of its own:\n%s", result)
+			}
+		})
+	}
+}
+
+func TestSizeLimitsExceeding(t *testing.T) {
+	tests := []struct {
+		name     string
+		diagram  string
+		limits   sizeLimits
+		parts    int
+		expected []int
+	}{
+		{name: "within the limits", diagram: componentsDiagram, limits: sizeLimits{nodes: 5}, parts: 2},
+		{
+			name: "class with too many relationships",
+			diagram: `@startuml
+namespace app {
+    class "Server" << (S,Aquamarine) >> {
+    }
+}
+
+"app.Server" o-- "time.Time"
+"app.Server" o-- "net.Listener"
+@enduml
+`,
+			limits:   sizeLimits{edges: 1},
+			parts:    1,
+			expected: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := partitionDiagram(ParseDiagram(tt.diagram), tt.limits)
+			if len(parts) != tt.parts {
+				t.Fatalf("partitionDiagram() = %d parts, want %d", len(parts), tt.parts)
+			}
+			if result := tt.limits.exceeding(parts); !slices.Equal(result, tt.expected) {
+				t.Errorf("exceeding() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
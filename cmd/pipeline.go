@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"strings"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// pipeline holds what go2uml changes the diagram goplantuml renders with: the options, the selected packages and
+// their sources, the settings parsed from the options and what is loaded from the sources on the way
+type pipeline struct {
+	opts             *options
+	renderingOptions map[goplantuml.RenderingOption]any
+	selection        *packageSelection
+	ignored          []string
+	build            *build.Context
+	sources          *sourceFiles
+	rules            []*rule
+	external         []string
+	stereotypes      map[string]string
+	thresholds       map[string]float64
+	members          memberFormat
+	collapser        packageCollapser
+	limits           sizeLimits
+	rulesRendered    string // the diagram of all relationships, see rendersAllRelationships
+
+	typed   *typedPackages
+	index   *sourceIndex
+	all     *Diagram // rulesDiagram, built once
+	metrics []*typeMetrics
+}
+
+// newPipeline reads the rules file, parses the options that are lists and collects the sources of the selected
+// directories
+func newPipeline(opts *options, selection *packageSelection, ignored []string) (*pipeline, error) {
+	p := &pipeline{
+		opts:      opts,
+		selection: selection,
+		ignored:   ignored,
+		build:     buildContext(opts.goos, opts.goarch, opts.tags),
+		external:  splitList(opts.external),
+		members:   memberFormat{signature: opts.signature, qualify: opts.qualify},
+		collapser: packageCollapser{names: splitList(opts.collapse), depth: opts.collapseDepth},
+		limits:    sizeLimits{nodes: opts.maxNodes, edges: opts.maxEdges},
+	}
+	if opts.stdlibInterfaces {
+		p.external = splitList(strings.Join(append(stdlibInterfaces(), p.external...), ","))
+	}
+	var err error
+	if opts.check != "" {
+		if p.rules, err = readRules(opts.check); err != nil {
+			return nil, err
+		}
+	}
+	if p.renderingOptions, err = opts.renderingOptions(); err != nil {
+		return nil, err
+	}
+	if p.stereotypes, err = parseStereotypeMapping(opts.interfaceStereotypes); err != nil {
+		return nil, err
+	}
+	if p.thresholds, err = parseMetricThresholds(opts.metricsThresholds); err != nil {
+		return nil, err
+	}
+	p.sources, err = collectSources(
+		selection.dirs,
+		selection.recursive,
+		ignored,
+		&sourceFilter{build: p.build, includeGenerated: opts.includeGenerated, excludeMocks: opts.excludeMocks},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// render parses the selected packages with goplantuml and renders them with the rendering options. The diagram of all
+// relationships is rendered from the same packages as well if it is needed.
+func (p *pipeline) render() (string, error) {
+	staged, err := stageDirectories(p.selection.dirs, p.ignored, p.sources)
+	if err != nil {
+		return "", err
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(
+		staged.dirs,
+		staged.ignored,
+		p.selection.recursive,
+		p.opts.maxDepth,
+	)
+	staged.remove()
+	if err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", errors.New("no classes found to generate diagram")
+	}
+	_ = result.SetRenderingOptions(p.renderingOptions)
+	rendered := result.Render()
+	if p.rendersAllRelationships() {
+		_ = result.SetRenderingOptions(rulesRenderingOptions(p.renderingOptions))
+		p.rulesRendered = result.Render()
+	}
+	return rendered, nil
+}
+
+// useTypes reports whether the packages are type-checked
+func (p *pipeline) useTypes() bool {
+	return p.opts.typecheck || len(p.external) > 0
+}
+
+// useStereotypes reports whether interfaces are mapped to stereotypes of their implementations
+func (p *pipeline) useStereotypes() bool {
+	return len(p.stereotypes) > 0 || p.sources.wellKnown && !p.opts.hideInterfaceStereotypes
+}
+
+// useMetrics reports whether the metrics of the types are computed
+func (p *pipeline) useMetrics() bool {
+	return p.opts.showMetrics || len(p.thresholds) > 0 || p.opts.metricsCSV != ""
+}
+
+// findsCycles reports whether cycles are searched
+func (p *pipeline) findsCycles() bool {
+	return p.opts.cycles || p.opts.highlightCycles
+}
+
+// rendersAllRelationships reports whether the diagram of all relationships is rendered, which -check evaluates its
+// rules against, cycles are searched in and coupling metrics are counted in
+func (p *pipeline) rendersAllRelationships() bool {
+	return p.opts.check != "" || p.findsCycles() || p.useMetrics()
+}
+
+// useSources reports whether the sources are parsed into a sourceIndex
+func (p *pipeline) useSources() bool {
+	o := p.opts
+	return o.showDocs || o.showTags.enabled || o.showReceivers || o.showDependencies ||
+		renderingOption(p.renderingOptions, goplantuml.RenderAggregations, false) ||
+		p.sources.directives || p.sources.deprecated || p.sources.assertions || p.useStereotypes() ||
+		o.sortMembers == sortSource || p.members.qualify == qualifyPath || p.useMetrics() || o.changedSince != "" ||
+		o.check != "" || p.findsCycles()
+}
+
+// transforms reports whether go2uml changes the diagram goplantuml renders or only converts it
+func (p *pipeline) transforms() bool {
+	o := p.opts
+	return p.useTypes() || p.useSources() || o.sortMembers != "" || o.maxMembers > 0 || p.members.enabled() ||
+		p.collapser.enabled() || o.splitBy != "" || p.limits.enabled() || o.check != "" || p.findsCycles()
+}
+
+// transformDiagram applies the options to the diagram goplantuml rendered. It returns the violations of the -check
+// rules, which it reports, and leaves the diagram unfinished if they are not to be marked.
+func transformDiagram(d *Diagram, p *pipeline) ([]*violation, error) {
+	if err := addSourceDetails(d, p); err != nil {
+		return nil, err
+	}
+	violations := []*violation{}
+	if p.opts.check != "" {
+		violations = p.violations()
+		reportViolations(os.Stderr, p.opts.check, violations)
+		if !p.opts.markViolations {
+			return violations, nil
+		}
+		markViolations(d, violations)
+	}
+	if err := finishDiagram(d, p); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+// addSourceDetails loads the type-checked packages and the sources and adds what they tell beyond goplantuml to the
+// diagram: relationships, member formats, stereotypes, docs, tags, receivers, directives, deprecations and metrics
+func addSourceDetails(d *Diagram, p *pipeline) error {
+	o := p.opts
+	repairRelationships(d, p.sources.dirs, p.selection.dirs)
+	if p.useTypes() {
+		typed, err := loadTypedPackages(p.selection.dirs, p.selection.recursive, p.ignored, p.external, p.build)
+		if err != nil {
+			return err
+		}
+		p.typed = typed
+		if o.typecheck {
+			applyTypedRelations(d, typed, p.renderingOptions)
+		}
+		if len(p.external) > 0 {
+			addExternalInterfaces(d, typed, p.renderingOptions)
+		}
+	}
+	if p.useSources() {
+		p.index = loadSourceIndex(p.sources, p.selection.dirs)
+	}
+	if p.members.enabled() {
+		formatMembers(d, p.index, p.members)
+	}
+	if renderingOption(p.renderingOptions, goplantuml.RenderAggregations, false) {
+		applyMultiplicities(d, p.index, o.aggregatePrivateMembers)
+	}
+	if p.sources.assertions {
+		addAssertions(d, p.index, p.renderingOptions)
+	}
+	if o.showDependencies {
+		addDependencies(d, p.index, o.showConnectionLabels)
+	}
+	if p.useStereotypes() {
+		addInterfaceStereotypes(d, p.index, interfaceStereotypes(d, p.index, p.stereotypes, o.hideInterfaceStereotypes))
+	}
+	if o.showDocs {
+		addDocs(d, p.index, o.docSummary)
+	}
+	if o.showTags.enabled {
+		addTags(d, p.index, o.showTags)
+	}
+	if o.showReceivers {
+		addReceivers(d, p.index)
+	}
+	if p.sources.directives {
+		applyDirectives(d, p.index)
+	}
+	if p.sources.deprecated {
+		applyDeprecations(d, p.index, o.hideDeprecated)
+	}
+	if p.useMetrics() {
+		p.metrics = computeMetrics(d, p.rulesDiagram(), p.index)
+	}
+	if o.metricsCSV != "" {
+		return writeMetricsCSV(o.metricsCSV, p.metrics)
+	}
+	return nil
+}
+
+// rulesDiagram returns the diagram of all relationships, with the relationships go2uml finds in the sources added
+func (p *pipeline) rulesDiagram() *Diagram {
+	if p.all == nil {
+		p.all = ParseDiagram(p.rulesRendered)
+		repairRelationships(p.all, p.sources.dirs, p.selection.dirs)
+		addRuleRelationships(p.all, p.typed, p.opts.typecheck, p.index, rulesRenderingOptions(p.renderingOptions))
+	}
+	return p.all
+}
+
+// violations evaluates the -check rules against the diagram of all relationships
+func (p *pipeline) violations() []*violation {
+	return checkRules(p.rulesDiagram(), p.index, p.rules)
+}
+
+// finishDiagram applies the options that work on the complete diagram: it reports and highlights cycles, marks the
+// changes since a revision, collapses packages, sorts and truncates members and shows the metrics
+func finishDiagram(d *Diagram, p *pipeline) error {
+	o := p.opts
+	if p.findsCycles() {
+		cycles := findCycles(p.rulesDiagram(), p.index)
+		reportCycles(os.Stderr, cycles)
+		if o.highlightCycles {
+			highlightCycles(d, cycles)
+		}
+	}
+	if o.changedSince != "" {
+		changes, err := changedLines(p.selection.dirs, o.changedSince)
+		if err != nil {
+			return err
+		}
+		changed := markChanges(d, p.index, changes)
+		if o.changedOnly {
+			limitToChanges(d, changed)
+		}
+	}
+	if p.collapser.enabled() {
+		collapsePackages(d, p.collapser)
+	}
+	if o.sortMembers != "" {
+		sortMembers(d, p.index, o.sortMembers)
+	}
+	if o.maxMembers > 0 {
+		truncateMembers(d, o.maxMembers)
+	}
+	if p.useMetrics() {
+		addMetrics(p.metrics, o.showMetrics, p.thresholds)
+	}
+	return nil
+}
+
+// writeDiagram converts the rendered diagram, or its parts if it was partitioned, to the format and writes it to the
+// output file, or to stdout if there is none
+func writeDiagram(rendered string, parts []*Diagram, format, output string) (err error) {
+	format = strings.ToLower(format)
+	if len(parts) > 1 {
+		rendered, err = renderParts(parts, format)
+	} else {
+		rendered, err = convertDiagram(rendered, format)
+	}
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = fmt.Fprint(os.Stdout, rendered)
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("could not write %s: %w", output, closeErr)
+		}
+	}()
+	if _, err := fmt.Fprint(f, rendered); err != nil {
+		return fmt.Errorf("could not write %s: %w", output, err)
+	}
+	return nil
+}
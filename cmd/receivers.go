@@ -0,0 +1,68 @@
+package main
+
+import (
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// Receiver kinds of methods, shown as stereotypes with -show-receivers
+const (
+	receiverPointer = "pointer"
+	receiverValue   = "value"
+)
+
+// receiverNotePosition is the side of its class the note about mixed receivers is drawn on
+const receiverNotePosition = "bottom"
+
+// receiverKind returns whether a method has a pointer or a value receiver
+func receiverKind(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	if _, ok := decl.Recv.List[0].Type.(*ast.StarExpr); ok {
+		return receiverPointer
+	}
+	return receiverValue
+}
+
+// addReceivers appends the receiver kind of every method declared in the sources as a stereotype to its member
+// line. Types that mix both kinds get a note listing the methods only the pointer type has, since the value type
+// does not implement interfaces that need them.
+func addReceivers(d *Diagram, idx *sourceIndex) {
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil || len(t.methods) == 0 {
+			continue
+		}
+		kinds := map[string]string{}
+		pointerOnly := []string{}
+		values := 0
+		for _, m := range t.methods {
+			kind := receiverKind(m.decl)
+			kinds[m.decl.Name.Name] = kind
+			if kind == receiverPointer {
+				pointerOnly = append(pointerOnly, m.decl.Name.Name)
+			} else {
+				values++
+			}
+		}
+		for i, m := range c.Members {
+			if !isMember(m) || !isMethod(m) {
+				continue
+			}
+			if kind, ok := kinds[memberName(m)]; ok {
+				c.Members[i] = m + " <<" + kind + ">>"
+			}
+		}
+		if values > 0 && len(pointerOnly) > 0 {
+			sort.Strings(pointerOnly)
+			d.Notes = append(d.Notes, &Note{
+				Position: receiverNotePosition,
+				Target:   c.Ref(),
+				Text: "mixes pointer and value receivers:\nonly *" + c.Name + " has " +
+					strings.Join(pointerOnly, ", "),
+			})
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// receiverSource declares a type with pointer receivers only and one that mixes both kinds
+const receiverSource = `package store
+
+type Store struct{}
+
+func (s *Store) Save() error { return nil }
+
+type Cache[T any] struct{}
+
+func (c Cache[T]) Len() int       { return 0 }
+func (c *Cache[T]) Put(v T)       {}
+func (c *Cache[T]) Clear() error  { return nil }
+`
+
+func TestAddReceivers(t *testing.T) {
+	root := writeFiles(t, map[string]string{"store/store.go": receiverSource})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	addReceivers(d, loadSourceIndex(sources, dirs))
+
+	tests := []struct {
+		class    string
+		expected []string
+	}{
+		{class: "store.Store", expected: []string{"+ Save() error <<pointer>>"}},
+		{
+			class:    "store.Cache",
+			expected: []string{"+ Len() int <<value>>", "+ Put(v T) <<pointer>>", "+ Clear() error <<pointer>>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.class, func(t *testing.T) {
+			c := d.FindClass(tt.class)
+			if c == nil {
+				t.Fatalf("class %s not found", tt.class)
+			}
+			for _, member := range tt.expected {
+				if !containsString(c.Members, member) {
+					t.Errorf("Members = %q, want %q", c.Members, member)
+				}
+			}
+		})
+	}
+
+	if len(d.Notes) != 1 {
+		t.Fatalf("Notes = %d, want 1", len(d.Notes))
+	}
+	expected := "mixes pointer and value receivers:\nonly *Cache has Clear, Put"
+	if n := d.Notes[0]; n.Target != "Cache_generic_T" || n.Text != expected {
+		t.Errorf("Note = %s %q, want Cache_generic_T %q", n.Target, n.Text, expected)
+	}
+}
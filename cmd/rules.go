@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// Kinds of architecture rules of -check
+const (
+	ruleMustNotDepend = "must not depend"
+	ruleMayOnlyDepend = "may only depend"
+	ruleMayImplement  = "may only implement"
+	ruleNoCycles      = "no cycles"
+)
+
+// violationColor is the color -mark-violations draws violating relationships in
+const violationColor = "#red"
+
+// rulePatterns are the sentences of the rules file, the first group is the package the rule applies to and the
+// second one the comma separated list of packages it allows or denies
+var rulePatterns = map[string]*regexp.Regexp{
+	ruleMustNotDepend: regexp.MustCompile(`^package (\S+) must not depend on (.+)$`),
+	ruleMayOnlyDepend: regexp.MustCompile(`^package (\S+) may only depend on (.+)$`),
+	ruleMayImplement:  regexp.MustCompile(`^types in (\S+) may only implement interfaces from (.+)$`),
+	ruleNoCycles:      regexp.MustCompile(`^no cycles between packages$`),
+}
+
+// rule is one line of a rules file
+type rule struct {
+	line     int
+	text     string
+	kind     string
+	packages string   // pattern of the packages the rule applies to
+	targets  []string // patterns of the packages the rule allows or denies
+}
+
+// violation is a breach of a rule by one or more relationships of the diagram
+type violation struct {
+	rule    *rule
+	message string
+	edges   []*Edge
+}
+
+// parseRules reads a rules file with one rule per line. Blank lines and lines starting with # are ignored, a
+// trailing period is optional. Package patterns are package names, namespace paths like store.sql or slash separated
+// paths like store/sql, which may contain the wildcards of path.Match.
+func parseRules(r io.Reader) ([]*rule, error) {
+	result := []*rule{}
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ".")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var parsed *rule
+		for kind, pattern := range rulePatterns {
+			match := pattern.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			parsed = &rule{line: number, text: text, kind: kind}
+			if len(match) > 2 {
+				parsed.packages = match[1]
+				parsed.targets = splitList(match[2])
+			}
+		}
+		if parsed == nil {
+			return nil, fmt.Errorf("line %d: unknown rule %q", number, text)
+		}
+		result = append(result, parsed)
+	}
+	return result, scanner.Err()
+}
+
+// readRules reads the rules file at path
+func readRules(path string) ([]*rule, error) {
+	file, err := os.Open(path) // #nosec G304 -- the rules file is given by the user
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Warn("could not close the rules file", "file", path, "error", err)
+		}
+	}()
+	rules, err := parseRules(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// matchesPackage reports whether a namespace path like store.sql matches a package pattern
+func matchesPackage(pattern, namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	slashed := strings.ReplaceAll(namespace, ".", "/")
+	pattern = strings.ReplaceAll(pattern, ".", "/")
+	if pattern == shortName(namespace) || pattern == slashed {
+		return true
+	}
+	matched, _ := path.Match(pattern, slashed)
+	return matched
+}
+
+// matchesAny reports whether a namespace path matches any of the package patterns
+func matchesAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if matchesPackage(pattern, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// packageGraph is the dependency graph of the classes of a diagram and of their namespaces
+type packageGraph struct {
+	classes map[string]*Class // classes by full name and by reference
+	edges   dependencyGraph
+}
+
+// namespace returns the namespace of a relationship end, which is the qualifier of its name for types that are not
+// part of the diagram
+func (g *packageGraph) namespace(name string) string {
+	if c, ok := g.classes[name]; ok {
+		return c.Namespace
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// newPackageGraph collects the dependencies between the namespaces of a diagram, through its relationships and,
+// if the index is not nil, through the imports of their packages
+func newPackageGraph(d *Diagram, idx *sourceIndex) *packageGraph {
+	g := &packageGraph{classes: map[string]*Class{}, edges: dependencyGraph{}}
+	for _, c := range d.AllClasses() {
+		g.classes[c.FullName()] = c
+		g.classes[c.Ref()] = c
+	}
+	for _, e := range d.Edges {
+		from, to := dependency(e)
+		fromPackage, toPackage := g.namespace(from), g.namespace(to)
+		if fromPackage == "" || toPackage == "" || fromPackage == toPackage {
+			continue
+		}
+		g.edges.add(fromPackage, toPackage, e)
+	}
+	if idx != nil {
+		for namespace, imported := range idx.importedNamespaces() {
+			for _, to := range imported {
+				g.edges.link(namespace, to)
+			}
+		}
+	}
+	return g
+}
+
+// cycles returns the groups of namespaces that depend on each other, see stronglyConnected
+func (g *packageGraph) cycles() [][]string {
+	return stronglyConnected(g.edges)
+}
+
+// checkDependencies evaluates a must not depend or may only depend rule against the relationships of the diagram and
+// against the imports between packages that no relationship backs
+func (g *packageGraph) checkDependencies(d *Diagram, r *rule) []*violation {
+	violates := func(fromPackage, toPackage string) bool {
+		return fromPackage != toPackage && matchesPackage(r.packages, fromPackage) &&
+			matchesAny(r.targets, toPackage) == (r.kind == ruleMustNotDepend)
+	}
+	result := []*violation{}
+	for _, e := range d.Edges {
+		from, to := dependency(e)
+		if violates(g.namespace(from), g.namespace(to)) {
+			result = append(result, &violation{
+				rule:    r,
+				message: fmt.Sprintf("%s depends on %s", from, to),
+				edges:   []*Edge{e},
+			})
+		}
+	}
+	packages := []string{}
+	for from := range g.edges {
+		packages = append(packages, from)
+	}
+	sort.Strings(packages)
+	for _, from := range packages {
+		imported := []string{}
+		for to, edges := range g.edges[from] {
+			if len(edges) == 0 && violates(from, to) {
+				imported = append(imported, to)
+			}
+		}
+		sort.Strings(imported)
+		for _, to := range imported {
+			result = append(result, &violation{rule: r, message: fmt.Sprintf("%s imports %s", from, to)})
+		}
+	}
+	return result
+}
+
+// checkRules evaluates the rules against the relationships of the diagram, in the order of the rules. Dependencies
+// and cycles between packages are also searched through their imports if the index is not nil.
+func checkRules(d *Diagram, idx *sourceIndex, rules []*rule) []*violation {
+	g := newPackageGraph(d, idx)
+	result := []*violation{}
+	for _, r := range rules {
+		switch r.kind {
+		case ruleMustNotDepend, ruleMayOnlyDepend:
+			result = append(result, g.checkDependencies(d, r)...)
+		case ruleMayImplement:
+			for _, e := range d.Edges {
+				if !strings.HasPrefix(e.Arrow, "<|") {
+					continue
+				}
+				if c, ok := g.classes[e.From]; ok && c.Kind != "interface" {
+					continue
+				}
+				if matchesPackage(r.packages, g.namespace(e.To)) && !matchesAny(r.targets, g.namespace(e.From)) {
+					result = append(result, &violation{
+						rule:    r,
+						message: fmt.Sprintf("%s implements %s", e.To, e.From),
+						edges:   []*Edge{e},
+					})
+				}
+			}
+		case ruleNoCycles:
+			for _, cycle := range g.cycles() {
+				result = append(result, &violation{
+					rule:    r,
+					message: "cycle between " + strings.Join(cycle, ", "),
+					edges:   g.edges.edges(cycle),
+				})
+			}
+		}
+	}
+	return result
+}
+
+// colorArrow returns a relationship arrow with an inline color, e.g. <|-[#red]- for <|--
+func colorArrow(arrow, color string) string {
+	if strings.Contains(arrow, "[") {
+		return arrow
+	}
+	i := strings.IndexAny(arrow, "-.")
+	if i < 0 {
+		return arrow
+	}
+	return arrow[:i+1] + "[" + color + "]" + arrow[i+1:]
+}
+
+// markViolations draws the relationships of the violations in red in the diagram. Violations are found in the
+// diagram of rulesRenderingOptions, so violating relationships the diagram does not draw are added to it.
+func markViolations(d *Diagram, violations []*violation) {
+	for _, v := range violations {
+		for _, violating := range v.edges {
+			marked := false
+			for _, e := range d.Edges {
+				if e.From == violating.From && e.To == violating.To && e.Arrow == violating.Arrow {
+					e.Arrow = colorArrow(e.Arrow, violationColor)
+					marked = true
+				}
+			}
+			if !marked && d.FindClass(violating.From) != nil && d.FindClass(violating.To) != nil {
+				e := *violating
+				e.Arrow = colorArrow(e.Arrow, violationColor)
+				d.AddEdge(&e)
+			}
+		}
+	}
+}
+
+// rulesRenderingOptions returns the rendering options of the diagram -check evaluates its rules against. Rules are
+// checked against all relationships, not only the ones drawn with the options given.
+func rulesRenderingOptions(ro map[goplantuml.RenderingOption]any) map[goplantuml.RenderingOption]any {
+	result := maps.Clone(ro)
+	for _, option := range []goplantuml.RenderingOption{
+		goplantuml.RenderAggregations,
+		goplantuml.AggregatePrivateMembers,
+		goplantuml.RenderAliases,
+		goplantuml.RenderCompositions,
+		goplantuml.RenderImplementations,
+	} {
+		result[option] = true
+	}
+	return result
+}
+
+// addRuleRelationships adds the relationships go2uml finds in the sources to the diagram -check evaluates its rules
+// against: the ones of the type-checked packages if typecheck is set, aggregations with multiplicities, assertions
+// and dependencies
+func addRuleRelationships(
+	d *Diagram,
+	typed *typedPackages,
+	typecheck bool,
+	idx *sourceIndex,
+	ro map[goplantuml.RenderingOption]any,
+) {
+	if typed != nil {
+		if typecheck {
+			applyTypedRelations(d, typed, ro)
+		}
+		addExternalInterfaces(d, typed, ro)
+	}
+	applyMultiplicities(d, idx, true)
+	addAssertions(d, idx, ro)
+	addDependencies(d, idx, renderingOption(ro, goplantuml.RenderConnectionLabels, false))
+}
+
+// reportViolations writes one line per violation, prefixed with the file and line of its rule
+func reportViolations(w io.Writer, file string, violations []*violation) {
+	for _, v := range violations {
+		fmt.Fprintf(w, "%s:%d: %s: %s\n", file, v.rule.line, v.rule.text, v.message)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// layeredDiagram has a domain package that depends on infra, which depends back on it, and an api package that
+// implements interfaces of ports and of domain
+const layeredDiagram = `@startuml
+namespace domain {
+    class "Order" << (S,Aquamarine) >> {
+    }
+    interface "Repository" {
+    }
+}
+namespace infra {
+    class "DB" << (S,Aquamarine) >> {
+    }
+}
+namespace ports {
+    interface "Handler" {
+    }
+}
+namespace api {
+    class "Server" << (S,Aquamarine) >> {
+    }
+}
+
+"domain.Order" "1" o-- "1" "infra.DB" : db
+"domain.Repository" <|-- "infra.DB"
+"ports.Handler" <|-- "api.Server"
+"domain.Repository" <|-- "api.Server"
+"api.Server" ..> "domain.Order"
+"api.Server" ..> "time.Time"
+@enduml
+`
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(strings.NewReader(`# layering
+package domain must not depend on infra
+
+package api may only depend on domain, ports.
+types in api may only implement interfaces from ports
+no cycles between packages
+`))
+	if err != nil {
+		t.Fatalf("parseRules() error = %v", err)
+	}
+	expected := []rule{
+		{line: 2, text: "package domain must not depend on infra", kind: ruleMustNotDepend, packages: "domain"},
+		{line: 4, text: "package api may only depend on domain, ports", kind: ruleMayOnlyDepend, packages: "api"},
+		{
+			line:     5,
+			text:     "types in api may only implement interfaces from ports",
+			kind:     ruleMayImplement,
+			packages: "api",
+		},
+		{line: 6, text: "no cycles between packages", kind: ruleNoCycles},
+	}
+	if len(rules) != len(expected) {
+		t.Fatalf("parseRules() = %d rules, want %d", len(rules), len(expected))
+	}
+	for i, r := range rules {
+		want := expected[i]
+		if r.line != want.line || r.text != want.text || r.kind != want.kind || r.packages != want.packages {
+			t.Errorf("rule %d = %+v, want %+v", i, *r, want)
+		}
+	}
+	if targets := rules[1].targets; !equalStrings(targets, []string{"domain", "ports"}) {
+		t.Errorf("targets = %q, want domain and ports", targets)
+	}
+
+	if _, err := parseRules(strings.NewReader("package domain should not depend on infra\n")); err == nil {
+		t.Errorf("parseRules() accepted an unknown rule")
+	}
+}
+
+func TestMatchesPackage(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		namespace string
+		expected  bool
+	}{
+		{pattern: "sql", namespace: "store.sql", expected: true},
+		{pattern: "store/sql", namespace: "store.sql", expected: true},
+		{pattern: "store.sql", namespace: "store.sql", expected: true},
+		{pattern: "store/*", namespace: "store.sql", expected: true},
+		{pattern: "store", namespace: "store.sql", expected: false},
+		{pattern: "store", namespace: "", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.namespace, func(t *testing.T) {
+			if result := matchesPackage(tt.pattern, tt.namespace); result != tt.expected {
+				t.Errorf("matchesPackage() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected []string
+	}{
+		{
+			rule:     "package domain must not depend on infra",
+			expected: []string{"domain.Order depends on infra.DB"},
+		},
+		{
+			rule:     "package api may only depend on domain, ports",
+			expected: []string{"api.Server depends on time.Time"},
+		},
+		{
+			rule:     "types in api may only implement interfaces from ports",
+			expected: []string{"api.Server implements domain.Repository"},
+		},
+		{
+			rule:     "no cycles between packages",
+			expected: []string{"cycle between domain, infra"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rules, err := parseRules(strings.NewReader(tt.rule))
+			if err != nil {
+				t.Fatalf("parseRules() error = %v", err)
+			}
+			messages := []string{}
+			for _, v := range checkRules(ParseDiagram(layeredDiagram), nil, rules) {
+				messages = append(messages, v.message)
+			}
+			if !equalStrings(messages, tt.expected) {
+				t.Errorf("checkRules() = %q, want %q", messages, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckRulesThroughImports(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/shop\n",
+		"domain/order.go": "package domain\n\nimport \"example.com/shop/infra\"\n\ntype Order struct{}\n\n" +
+			"func (o *Order) Save() { infra.Store() }\n",
+		"infra/db.go": "package infra\n\ntype DB struct{}\n\nfunc Store() {}\n",
+	})
+	dirs := []string{root}
+	sources, err := collectSources(dirs, true, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	d := ParseDiagram(`@startuml
+namespace domain {
+    class "Order" << (S,Aquamarine) >> {
+    }
+}
+namespace infra {
+    class "DB" << (S,Aquamarine) >> {
+    }
+}
+@enduml
+`)
+	rules, err := parseRules(strings.NewReader("package domain must not depend on infra\n" +
+		"package domain may only depend on ports\npackage infra must not depend on domain"))
+	if err != nil {
+		t.Fatalf("parseRules() error = %v", err)
+	}
+	if violations := checkRules(d, nil, rules); len(violations) != 0 {
+		t.Errorf("checkRules() without index = %d violations, want none", len(violations))
+	}
+	messages := []string{}
+	for _, v := range checkRules(d, loadSourceIndex(sources, dirs), rules) {
+		messages = append(messages, v.rule.text+": "+v.message)
+	}
+	expected := []string{
+		"package domain must not depend on infra: domain imports infra",
+		"package domain may only depend on ports: domain imports infra",
+	}
+	if !equalStrings(messages, expected) {
+		t.Errorf("checkRules() = %q, want %q", messages, expected)
+	}
+}
+
+func TestMarkViolations(t *testing.T) {
+	d := ParseDiagram(layeredDiagram)
+	rules, err := parseRules(strings.NewReader("no cycles between packages\n"))
+	if err != nil {
+		t.Fatalf("parseRules() error = %v", err)
+	}
+	violations := checkRules(d, nil, rules)
+	markViolations(d, violations)
+
+	expected := []string{
+		`"domain.Order" "1" o-[#red]- "1" "infra.DB" : db`,
+		`"domain.Repository" <|-[#red]- "infra.DB"`,
+		`"ports.Handler" <|-- "api.Server"`,
+	}
+	for i, want := range expected {
+		if line := d.Edges[i].String(); line != want {
+			t.Errorf("edge %d = %s, want %s", i, line, want)
+		}
+	}
+	if edge := ParseEdge(d.Edges[0].String()); edge == nil || edge.Arrow != "o-[#red]-" {
+		t.Errorf("ParseEdge() = %+v, want the colored arrow", edge)
+	}
+
+	var report bytes.Buffer
+	reportViolations(&report, "arch.rules", violations)
+	if expected := "arch.rules:1: no cycles between packages: cycle between domain, infra\n"; report.String() != expected {
+		t.Errorf("reportViolations() = %q, want %q", report.String(), expected)
+	}
+}
+
+func TestMarkViolationsNotDrawn(t *testing.T) {
+	rules, err := parseRules(strings.NewReader("no cycles between packages\n"))
+	if err != nil {
+		t.Fatalf("parseRules() error = %v", err)
+	}
+	violations := checkRules(ParseDiagram(layeredDiagram), nil, rules)
+	d := ParseDiagram(layeredDiagram)
+	d.RemoveEdges(func(e *Edge) bool { return strings.HasPrefix(e.Arrow, "o") })
+	markViolations(d, violations)
+
+	expected := []string{
+		`"domain.Repository" <|-[#red]- "infra.DB"`,
+		`"ports.Handler" <|-- "api.Server"`,
+		`"domain.Repository" <|-- "api.Server"`,
+		`"api.Server" ..> "domain.Order"`,
+		`"api.Server" ..> "time.Time"`,
+		`"domain.Order" "1" o-[#red]- "1" "infra.DB" : db`,
+	}
+	edges := []string{}
+	for _, e := range d.Edges {
+		edges = append(edges, e.String())
+	}
+	if !equalStrings(edges, expected) {
+		t.Errorf("edges = %q, want %q", edges, expected)
+	}
+}
+
+func TestRulesRenderingOptions(t *testing.T) {
+	ro := map[goplantuml.RenderingOption]any{
+		goplantuml.RenderAggregations:    false,
+		goplantuml.RenderImplementations: false,
+		goplantuml.RenderFields:          false,
+	}
+	result := rulesRenderingOptions(ro)
+	for _, option := range []goplantuml.RenderingOption{
+		goplantuml.RenderAggregations,
+		goplantuml.AggregatePrivateMembers,
+		goplantuml.RenderAliases,
+		goplantuml.RenderCompositions,
+		goplantuml.RenderImplementations,
+	} {
+		if result[option] != true {
+			t.Errorf("option %v = %v, want true", option, result[option])
+		}
+	}
+	if result[goplantuml.RenderFields] != false {
+		t.Errorf("RenderFields = %v, want the option given", result[goplantuml.RenderFields])
+	}
+	if ro[goplantuml.RenderAggregations] != false || len(ro) != 3 {
+		t.Errorf("rulesRenderingOptions() changed the options given: %v", ro)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Signature modes of -signature
+const (
+	signatureFull  = "full"
+	signatureTypes = "types"
+	signatureNames = "names"
+	signatureNone  = "none"
+)
+
+// Qualification modes of -qualify
+const (
+	qualifyNone    = "none"
+	qualifyPackage = "package"
+	qualifyPath    = "path"
+)
+
+var (
+	// signatureModes are the values -signature accepts
+	signatureModes = []string{signatureFull, signatureTypes, signatureNames, signatureNone}
+	// qualifyModes are the values -qualify accepts
+	qualifyModes = []string{qualifyNone, qualifyPackage, qualifyPath}
+	// qualifiedName matches type names in type expressions, with an optional package qualifier, e.g. http.Request
+	qualifiedName = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?`)
+	// markup matches the HTML tags goplantuml wraps keywords in, e.g. <font color=blue>map</font>
+	markup = regexp.MustCompile(`<[^<>]*>`)
+)
+
+// memberFormat is how -signature and -qualify render the fields and methods of classes
+type memberFormat struct {
+	signature string
+	qualify   string
+}
+
+// enabled reports whether members are rendered differently from goplantuml
+func (f memberFormat) enabled() bool {
+	return f.signature != signatureFull || f.qualify != ""
+}
+
+// typeQualifier qualifies the type names of the members of one class
+type typeQualifier struct {
+	mode       string
+	pkg        string            // the qualifier of types of the class's own package
+	imports    map[string]string // import paths by package name, for -qualify=path
+	typeParams map[string]bool
+}
+
+// qualify rewrites the type names of a type expression, e.g. *Request, *http.Request or *net/http.Request
+func (q *typeQualifier) qualify(expr string) string {
+	if q == nil || q.mode == "" {
+		return expr
+	}
+	result, start := "", 0
+	for _, tag := range markup.FindAllStringIndex(expr, -1) {
+		result += q.qualifyNames(expr[start:tag[0]]) + expr[tag[0]:tag[1]]
+		start = tag[1]
+	}
+	return result + q.qualifyNames(expr[start:])
+}
+
+// qualifyNames rewrites the type names of a type expression without markup
+func (q *typeQualifier) qualifyNames(expr string) string {
+	return qualifiedName.ReplaceAllStringFunc(expr, func(name string) string {
+		pkg, typeName, qualified := strings.Cut(name, ".")
+		if !qualified {
+			if token.IsKeyword(name) || types.Universe.Lookup(name) != nil || q.typeParams[name] {
+				return name
+			}
+			typeName = name
+		}
+		switch q.mode {
+		case qualifyNone:
+			return typeName
+		case qualifyPackage:
+			if !qualified && q.pkg != "" {
+				return q.pkg + "." + typeName
+			}
+		case qualifyPath:
+			if !qualified && q.pkg != "" {
+				return q.pkg + "." + typeName
+			}
+			if importPath, ok := q.imports[pkg]; ok && qualified {
+				return importPath + "." + typeName
+			}
+		}
+		return name
+	})
+}
+
+// splitTopLevel splits a list at the commas that are not nested in brackets, e.g. "a int, f func(int, string)"
+func splitTopLevel(list string) []string {
+	result := []string{}
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(list[start:]); rest != "" || len(result) > 0 {
+		result = append(result, rest)
+	}
+	return result
+}
+
+// splitParameter splits a parameter like "u *User" into its name and type. The name is empty for parameters
+// that only have a type, e.g. "chan int".
+func splitParameter(param string) (string, string) {
+	name, typ, ok := strings.Cut(strings.TrimSpace(param), " ")
+	if !ok || !token.IsIdentifier(name) {
+		return "", strings.TrimSpace(param)
+	}
+	return name, strings.TrimSpace(typ)
+}
+
+// formatMember renders a field or method line of goplantuml, e.g. "+ Save(u *User) error", with the given amount
+// of signature and qualified type names
+func formatMember(member, signature string, q *typeQualifier) string {
+	text := strings.TrimLeft(member, "+-#~ ")
+	visibility := member[:len(member)-len(text)]
+	name := memberName(member)
+	rest := strings.TrimSpace(text[len(name):])
+
+	if !strings.HasPrefix(rest, "(") {
+		if signature == signatureNames || signature == signatureNone || rest == "" {
+			return visibility + name
+		}
+		return visibility + name + " " + q.qualify(rest)
+	}
+
+	depth, end := 0, len(rest)
+	for i, r := range rest {
+		if r == '(' {
+			depth++
+		} else if r == ')' {
+			depth--
+			if depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	params := []string{}
+	for _, param := range splitTopLevel(rest[1:end]) {
+		paramName, paramType := splitParameter(param)
+		switch {
+		case signature == signatureTypes || signature == signatureNames && paramName == "":
+			params = append(params, q.qualify(paramType))
+		case signature == signatureNames:
+			params = append(params, paramName)
+		case paramName == "":
+			params = append(params, q.qualify(paramType))
+		default:
+			params = append(params, paramName+" "+q.qualify(paramType))
+		}
+	}
+	if signature == signatureNone {
+		params = nil
+	}
+	line := visibility + name + "(" + strings.Join(params, ", ") + ")"
+	if results := strings.TrimSpace(rest[min(end+1, len(rest)):]); results != "" &&
+		(signature == signatureFull || signature == signatureTypes) {
+		line += " " + q.qualify(results)
+	}
+	return line
+}
+
+// classQualifier returns the qualifier for the members of a class. The import paths -qualify=path needs are taken
+// from the sources, types of classes that are not declared in them keep their package qualifier.
+func classQualifier(c *Class, idx *sourceIndex, mode string, modules map[string]*module) *typeQualifier {
+	q := &typeQualifier{mode: mode, typeParams: map[string]bool{}, imports: map[string]string{}}
+	for _, s := range c.Stereotypes {
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			for _, param := range strings.Split(strings.Trim(s, "[]"), ",") {
+				q.typeParams[strings.TrimSpace(param)] = true
+			}
+		}
+	}
+	if c.SourceNamespace() != "" {
+		q.pkg = shortName(c.SourceNamespace())
+	}
+	if mode != qualifyPath || idx == nil {
+		return q
+	}
+	t := idx.lookup(c)
+	if t == nil {
+		return q
+	}
+	files := []*ast.File{t.file}
+	for _, m := range t.methods {
+		files = append(files, m.file)
+	}
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+				q.imports[importName(spec, importPath)] = importPath
+			}
+		}
+	}
+	if dir, ok := idx.dirs[c.SourceNamespace()]; ok {
+		m, ok := modules[dir]
+		if !ok {
+			m, _ = findModule(dir)
+			modules[dir] = m
+		}
+		if m != nil {
+			if importPath, err := m.importPath(filepath.Clean(dir)); err == nil {
+				q.pkg = importPath
+			}
+		}
+	}
+	return q
+}
+
+// formatMembers renders the fields and methods of every class with the amount of signature and the
+// qualification of type names of the format
+func formatMembers(d *Diagram, idx *sourceIndex, f memberFormat) {
+	modules := map[string]*module{}
+	for _, c := range d.AllClasses() {
+		q := classQualifier(c, idx, f.qualify, modules)
+		for i, m := range c.Members {
+			if isMember(m) {
+				c.Members[i] = formatMember(m, f.signature, q)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatMember(t *testing.T) {
+	qualifier := func(mode string) *typeQualifier {
+		return &typeQualifier{
+			mode:       mode,
+			pkg:        "store",
+			imports:    map[string]string{"http": "net/http"},
+			typeParams: map[string]bool{"T": true},
+		}
+	}
+	method := "+ Serve(w http.ResponseWriter, r *http.Request, users []*User, <font color=blue>func</font>(T) error) (int, error)"
+
+	tests := []struct {
+		name      string
+		member    string
+		signature string
+		qualify   string
+		expected  string
+	}{
+		{
+			name:      "unchanged",
+			member:    method,
+			signature: signatureFull,
+			expected:  method,
+		},
+		{
+			name:      "types",
+			member:    method,
+			signature: signatureTypes,
+			expected:  "+ Serve(http.ResponseWriter, *http.Request, []*User, <font color=blue>func</font>(T) error) (int, error)",
+		},
+		{
+			name:      "names",
+			member:    method,
+			signature: signatureNames,
+			expected:  "+ Serve(w, r, users, <font color=blue>func</font>(T) error)",
+		},
+		{
+			name:      "none",
+			member:    method,
+			signature: signatureNone,
+			expected:  "+ Serve()",
+		},
+		{
+			name:      "unqualified",
+			member:    method,
+			signature: signatureTypes,
+			qualify:   qualifyNone,
+			expected:  "+ Serve(ResponseWriter, *Request, []*User, <font color=blue>func</font>(T) error) (int, error)",
+		},
+		{
+			name:      "package",
+			member:    method,
+			signature: signatureTypes,
+			qualify:   qualifyPackage,
+			expected:  "+ Serve(http.ResponseWriter, *http.Request, []*store.User, <font color=blue>func</font>(T) error) (int, error)",
+		},
+		{
+			name:      "path",
+			member:    "- users <font color=blue>map</font>[string]*User",
+			signature: signatureFull,
+			qualify:   qualifyPath,
+			expected:  "- users <font color=blue>map</font>[string]*store.User",
+		},
+		{
+			name:      "imported path",
+			member:    "+ Handler http.Handler",
+			signature: signatureTypes,
+			qualify:   qualifyPath,
+			expected:  "+ Handler net/http.Handler",
+		},
+		{
+			name:      "field without type",
+			member:    "+ Handler http.Handler",
+			signature: signatureNames,
+			expected:  "+ Handler",
+		},
+		{
+			name:      "unnamed parameters",
+			member:    "+ ServeHTTP( http.ResponseWriter,  *http.Request) ",
+			signature: signatureNames,
+			expected:  "+ ServeHTTP(http.ResponseWriter, *http.Request)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatMember(tt.member, tt.signature, qualifier(tt.qualify))
+			if result != tt.expected {
+				t.Errorf("formatMember() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatMembersPath(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/app\n",
+		"store/store.go": `package store
+
+import (
+	"context"
+
+	m "example.com/app/model"
+)
+
+type Store struct {
+	Owner *m.User
+}
+
+func (s *Store) Find(ctx context.Context, id ID) *m.User { return nil }
+
+type ID int
+`,
+	})
+	dirs := []string{filepath.Join(root, "store")}
+	sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	c := &Class{Kind: "class", Name: "Store", Namespace: "store", Members: []string{
+		"+ Owner *m.User", "", "+ Find(ctx context.Context, id ID) *m.User", "",
+	}}
+	d := &Diagram{Namespaces: []*Namespace{{Name: "store", Path: "store", Classes: []*Class{c}}}}
+	formatMembers(d, loadSourceIndex(sources, dirs), memberFormat{signature: signatureTypes, qualify: qualifyPath})
+
+	expected := []string{
+		"+ Owner *example.com/app/model.User", "",
+		"+ Find(context.Context, example.com/app/store.ID) *example.com/app/model.User", "",
+	}
+	if !equalStrings(c.Members, expected) {
+		t.Errorf("Members = %q, want %q", c.Members, expected)
+	}
+}
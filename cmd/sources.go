@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// buildContext returns the build context for the given GOOS, GOARCH and comma separated build tags. Empty values
+// fall back to the ones of the go tool.
+func buildContext(goos, goarch, tags string) *build.Context {
+	ctx := build.Default
+	if goos != "" {
+		ctx.GOOS = goos
+	}
+	if goarch != "" {
+		ctx.GOARCH = goarch
+	}
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ' ' }) {
+		ctx.BuildTags = append(ctx.BuildTags, tag)
+	}
+	return &ctx
+}
+
+// mockPackages are the packages imported by mocks generated with mockgen or mockery
+var mockPackages = []string{
+	"github.com/golang/mock/gomock",
+	"go.uber.org/mock/gomock",
+	"github.com/stretchr/testify/mock",
+}
+
+// sourceFilter decides which Go files of the input directories are drawn in the diagram. If a build context is
+// set, files are matched against its GOOS, GOARCH and build tags the same way the go tool does.
+type sourceFilter struct {
+	build            *build.Context
+	includeGenerated bool
+	excludeMocks     bool
+}
+
+// includeSource reports whether the Go file at path with the content src is drawn. Files that cannot be parsed are
+// kept, so that goplantuml reports them as before.
+func (f *sourceFilter) includeSource(path string, src []byte) (bool, error) {
+	if f.build != nil {
+		match, err := f.build.MatchFile(filepath.Dir(path), filepath.Base(path))
+		if err != nil {
+			return false, fmt.Errorf("could not match build constraints of %s: %w", path, err)
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return true, nil
+	}
+	if !f.includeGenerated && ast.IsGenerated(file) {
+		return false, nil
+	}
+	if f.excludeMocks && isMock(file) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// isMock reports whether a file was generated by mockgen or mockery, from its header or the mock packages it imports
+func isMock(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		text := strings.ToLower(group.Text())
+		if strings.Contains(text, "mockgen") || strings.Contains(text, "mockery") {
+			return true
+		}
+	}
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err == nil && containsString(mockPackages, importPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceFiles are the Go files of the input directories that pass the source filter, grouped by directory
+type sourceFiles struct {
+	dirs       []string            // every walked directory, in walk order
+	files      map[string][]string // included Go files by directory
+	excluded   int                 // number of Go files left out by the filter
+	directives bool                // whether an included file contains a go2uml directive
+	deprecated bool                // whether an included file contains a deprecation notice
+	assertions bool                // whether an included file may assert that a type implements an interface
+	wellKnown  bool                // whether an included file may declare a method of a well-known interface
+}
+
+// collectSources walks the input directories the same way goplantuml does and applies the source filter to their
+// Go files
+func collectSources(dirs []string, recursive bool, ignored []string, filter *sourceFilter) (*sourceFiles, error) {
+	result := &sourceFiles{files: map[string][]string{}}
+	for _, root := range dirs {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != root && (!recursive || strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" ||
+					isInsideAny(p, ignored)) {
+					return filepath.SkipDir
+				}
+				if _, ok := result.files[p]; !ok {
+					result.files[p] = []string{}
+					result.dirs = append(result.dirs, p)
+				}
+				return nil
+			}
+			if filepath.Ext(p) != ".go" {
+				return nil
+			}
+			src, err := os.ReadFile(p) // #nosec G304 -- the file is found in a directory given by the user
+			if err != nil {
+				return fmt.Errorf("could not read %s: %w", p, err)
+			}
+			include, err := filter.includeSource(p, src)
+			if err != nil {
+				return err
+			}
+			if !include {
+				result.excluded++
+				return nil
+			}
+			if bytes.Contains(src, []byte(directivePrefix)) {
+				result.directives = true
+			}
+			if bytes.Contains(src, []byte(deprecatedMarker)) {
+				result.deprecated = true
+			}
+			if assertionPattern.Match(src) {
+				result.assertions = true
+			}
+			if wellKnownMethods.Match(src) {
+				result.wellKnown = true
+			}
+			dir := filepath.Dir(p)
+			result.files[dir] = append(result.files[dir], p)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not walk directory %s: %w", root, err)
+		}
+	}
+	return result, nil
+}
+
+// stagedDirectories are the input directories handed to goplantuml. When the source filter leaves out files, they
+// point into a temporary copy of the input that only holds the included files.
+type stagedDirectories struct {
+	dirs    []string
+	ignored []string
+	root    string
+}
+
+// remove deletes the temporary copy, if one was made
+func (s *stagedDirectories) remove() {
+	if s.root != "" {
+		_ = os.RemoveAll(s.root)
+	}
+}
+
+// stageDirectories prepares the input directories for goplantuml. goplantuml parses whole directories, so if files
+// are left out, the included ones are copied to a temporary directory under their absolute path. This keeps the
+// directory names goplantuml derives the namespaces from.
+func stageDirectories(dirs []string, ignored []string, sources *sourceFiles) (*stagedDirectories, error) {
+	if sources.excluded == 0 {
+		return &stagedDirectories{dirs: dirs, ignored: ignored}, nil
+	}
+
+	staging, err := os.MkdirTemp("", "go2uml-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %w", err)
+	}
+	result := &stagedDirectories{root: staging}
+	mirror := func(p string) string {
+		return filepath.Join(staging, strings.TrimPrefix(p, filepath.VolumeName(p)))
+	}
+	for _, dir := range sources.dirs {
+		if err := os.MkdirAll(mirror(dir), 0o750); err != nil {
+			result.remove()
+			return nil, fmt.Errorf("could not create temporary directory: %w", err)
+		}
+		for _, file := range sources.files[dir] {
+			if err := copyFile(file, mirror(file)); err != nil {
+				result.remove()
+				return nil, err
+			}
+		}
+	}
+	for _, dir := range dirs {
+		result.dirs = append(result.dirs, mirror(dir))
+	}
+	for _, dir := range ignored {
+		result.ignored = append(result.ignored, mirror(dir))
+	}
+	return result, nil
+}
+
+// copyFile copies the file src to dst
+func copyFile(src, dst string) error {
+	content, err := os.ReadFile(src) // #nosec G304 -- the file is found in a directory given by the user
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", src, err)
+	}
+	if err := os.WriteFile(dst, content, 0o600); err != nil {
+		return fmt.Errorf("could not write %s: %w", dst, err)
+	}
+	return nil
+}
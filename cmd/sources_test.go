@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+const (
+	userSource  = "package store\n\ntype User struct {\n\tName string\n}\n"
+	protoSource = "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage store\n\ntype UserMessage struct{}\n"
+	mockSource  = "// Code generated by MockGen. DO NOT EDIT.\n\npackage store\n\n" +
+		"import gomock \"go.uber.org/mock/gomock\"\n\ntype MockStore struct {\n\tctrl *gomock.Controller\n}\n"
+	mockeryLegacySource = "package store\n\nimport \"github.com/stretchr/testify/mock\"\n\n" +
+		"type MockRepository struct {\n\tmock.Mock\n}\n"
+)
+
+func TestSourceFilter(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"user.go":    userSource,
+		"proto.go":   protoSource,
+		"mock.go":    mockSource,
+		"mockery.go": mockeryLegacySource,
+		"broken.go":  "package store\n\nfunc {",
+	})
+
+	tests := []struct {
+		name     string
+		filter   sourceFilter
+		included []string
+	}{
+		{
+			name:     "generated files are skipped by default",
+			filter:   sourceFilter{},
+			included: []string{"broken.go", "mockery.go", "user.go"},
+		},
+		{
+			name:     "generated files included",
+			filter:   sourceFilter{includeGenerated: true},
+			included: []string{"broken.go", "mock.go", "mockery.go", "proto.go", "user.go"},
+		},
+		{
+			name:     "mocks excluded",
+			filter:   sourceFilter{includeGenerated: true, excludeMocks: true},
+			included: []string{"broken.go", "proto.go", "user.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			included := []string{}
+			for _, name := range []string{"broken.go", "mock.go", "mockery.go", "proto.go", "user.go"} {
+				ok, err := includeFile(&tt.filter, filepath.Join(root, name))
+				if err != nil {
+					t.Fatalf("includeSource() error = %v", err)
+				}
+				if ok {
+					included = append(included, name)
+				}
+			}
+			if !equalStrings(included, tt.included) {
+				t.Errorf("included = %v, want %v", included, tt.included)
+			}
+		})
+	}
+}
+
+func TestStageDirectories(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"store/user.go":        userSource,
+		"store/proto.go":       protoSource,
+		"store/mocks/mock.go":  mockSource,
+		"api/api.go":           "package api\n\ntype Handler struct{}\n",
+		"ignored/ignored.go":   "package ignored\n\ntype Ignored struct{}\n",
+		"store/.hidden/gen.go": protoSource,
+	})
+
+	ignored := []string{filepath.Join(root, "ignored")}
+	sources, err := collectSources([]string{root}, true, ignored, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	staged, err := stageDirectories([]string{root}, ignored, sources)
+	if err != nil {
+		t.Fatalf("stageDirectories() error = %v", err)
+	}
+	defer staged.remove()
+	if staged.root == "" {
+		t.Fatal("stageDirectories() did not copy the input although files are excluded")
+	}
+	if filepath.Base(staged.dirs[0]) != filepath.Base(root) {
+		t.Errorf("staged root %s does not keep the name of %s", staged.dirs[0], root)
+	}
+
+	result, err := goplantuml.NewClassDiagramWithMaxDepth(staged.dirs, staged.ignored, true, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the staged directories: %v", err)
+	}
+	rendered := result.Render()
+	for _, expected := range []string{`"User"`, `"Handler"`} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("diagram is missing %s:\n%s", expected, rendered)
+		}
+	}
+	for _, unexpected := range []string{"UserMessage", "MockStore", "Ignored"} {
+		if strings.Contains(rendered, unexpected) {
+			t.Errorf("diagram contains %s:\n%s", unexpected, rendered)
+		}
+	}
+}
+
+func TestStageDirectoriesWithoutExcludedFiles(t *testing.T) {
+	root := writeFiles(t, map[string]string{"store/user.go": userSource})
+
+	sources, err := collectSources([]string{root}, true, nil, &sourceFilter{})
+	if err != nil {
+		t.Fatalf("collectSources() error = %v", err)
+	}
+	staged, err := stageDirectories([]string{root}, nil, sources)
+	if err != nil {
+		t.Fatalf("stageDirectories() error = %v", err)
+	}
+	if staged.root != "" || !equalStrings(staged.dirs, []string{root}) {
+		t.Errorf("stageDirectories() = %+v, want the input directories", staged)
+	}
+}
+
+func TestSourceFilterBuildConstraints(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"conn.go":         "package net\n\ntype Dialer struct{}\n",
+		"conn_linux.go":   "package net\n\ntype conn struct{ fd int }\n",
+		"conn_windows.go": "package net\n\ntype conn struct{ handle uintptr }\n",
+		"conn_arm64.go":   "package net\n\ntype armConn struct{}\n",
+		"integration.go":  "//go:build integration\n\npackage net\n\ntype Fixture struct{}\n",
+		"broken.go":       "package net\n\nfunc {",
+	})
+	all := []string{"broken.go", "conn.go", "conn_arm64.go", "conn_linux.go", "conn_windows.go", "integration.go"}
+
+	tests := []struct {
+		name     string
+		goos     string
+		goarch   string
+		tags     string
+		included []string
+	}{
+		{
+			name:     "linux",
+			goos:     "linux",
+			goarch:   "amd64",
+			included: []string{"broken.go", "conn.go", "conn_linux.go"},
+		},
+		{
+			name:     "windows on arm64",
+			goos:     "windows",
+			goarch:   "arm64",
+			included: []string{"broken.go", "conn.go", "conn_arm64.go", "conn_windows.go"},
+		},
+		{
+			name:     "build tag",
+			goos:     "linux",
+			goarch:   "amd64",
+			tags:     "integration,other",
+			included: []string{"broken.go", "conn.go", "conn_linux.go", "integration.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &sourceFilter{build: buildContext(tt.goos, tt.goarch, tt.tags)}
+			included := []string{}
+			for _, name := range all {
+				ok, err := includeFile(filter, filepath.Join(root, name))
+				if err != nil {
+					t.Fatalf("includeSource() error = %v", err)
+				}
+				if ok {
+					included = append(included, name)
+				}
+			}
+			if !equalStrings(included, tt.included) {
+				t.Errorf("included = %v, want %v", included, tt.included)
+			}
+		})
+	}
+}
+
+// includeFile reads the Go file at path and reports whether the filter draws it
+func includeFile(filter *sourceFilter, path string) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return filter.includeSource(path, src)
+}
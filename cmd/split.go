@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// splitPackage is the value of -split that writes one diagram per Go package
+const splitPackage = "package"
+
+// indexName is the name of the diagram linking the diagrams of the packages. The underscore keeps it apart from the
+// diagrams of packages, which the go tool ignores in directories starting with one.
+const indexName = "_index"
+
+// namedDiagram is one of the diagrams written by -split, name is its file name without extension
+type namedDiagram struct {
+	name    string
+	diagram *Diagram
+}
+
+// diagramExtension returns the file extension of diagrams of a format
+func diagramExtension(format string) string {
+	if format == "mermaid" {
+		return ".mmd"
+	}
+	return ".puml"
+}
+
+// link returns a PlantUML link to the diagram with the given file name
+func link(file string) string {
+	return "[[" + file + "]]"
+}
+
+// splitDiagram splits a diagram into one diagram per namespace and an index diagram, which draws every namespace as
+// a node linking its diagram. The diagram of a namespace shows its classes in full and the classes of other
+// namespaces it has relationships with as stubs without members, linking their own diagram. Classes of external
+// interfaces and classes outside of namespaces are shown in full wherever they are referenced.
+func splitDiagram(d *Diagram, ext string) []namedDiagram {
+	owners := map[string]*Namespace{} // class names and aliases to the namespace that gets their diagram
+	classes := map[string]*Class{}
+	packages := []*Namespace{}
+	var walk func(namespaces []*Namespace)
+	walk = func(namespaces []*Namespace) {
+		for _, ns := range namespaces {
+			owned := false
+			for _, c := range ns.Classes {
+				classes[c.FullName()] = c
+				classes[c.Ref()] = c
+				if !containsString(plainStereotypes(c), externalStereotype) {
+					owners[c.FullName()] = ns
+					owners[c.Ref()] = ns
+					owned = true
+				}
+			}
+			if owned {
+				packages = append(packages, ns)
+			}
+			walk(ns.Children)
+		}
+	}
+	walk(d.Namespaces)
+	for _, c := range d.Classes {
+		classes[c.FullName()] = c
+		classes[c.Ref()] = c
+	}
+
+	result := []namedDiagram{}
+	for _, pkg := range packages {
+		pd := &Diagram{Header: append([]string{}, d.Header...), Footer: append([]string{}, d.Footer...)}
+		shown := map[*Class]bool{}
+		show := func(c *Class) {
+			if shown[c] {
+				return
+			}
+			shown[c] = true
+			owner := owners[c.Ref()]
+			if owner != nil && owner != pkg {
+				c = &Class{
+					Kind:        c.Kind,
+					Name:        c.Name,
+					Alias:       c.Alias,
+					Stereotypes: c.Stereotypes,
+					Extra:       link(owner.Path + ext),
+					Namespace:   c.Namespace,
+				}
+			}
+			pd.AddClass(c, owner != nil && owner.Group)
+		}
+		for _, c := range pkg.Classes {
+			if owners[c.Ref()] == pkg {
+				show(c)
+			}
+		}
+		for _, e := range d.Edges {
+			if owners[e.From] != pkg && owners[e.To] != pkg {
+				continue
+			}
+			pd.Edges = append(pd.Edges, e)
+			for _, end := range []string{e.From, e.To} {
+				if c, ok := classes[end]; ok {
+					show(c)
+				}
+			}
+		}
+		for _, n := range d.Notes {
+			class, _, _ := strings.Cut(n.Target, "::")
+			if owners[class] == pkg {
+				pd.Notes = append(pd.Notes, n)
+			}
+		}
+		result = append(result, namedDiagram{name: pkg.Path, diagram: pd})
+	}
+	return append(result, namedDiagram{name: indexName, diagram: indexDiagram(d, packages, owners, ext)})
+}
+
+// indexDiagram returns a diagram with one node per namespace, showing its number of exported types and linking its
+// diagram, and the relationships between the namespaces merged the way -collapse merges them
+func indexDiagram(d *Diagram, packages []*Namespace, owners map[string]*Namespace, ext string) *Diagram {
+	index := &Diagram{Header: append([]string{}, d.Header...), Footer: append([]string{}, d.Footer...)}
+	nodes := map[*Namespace]*Class{}
+	for _, pkg := range packages {
+		exported := 0
+		for _, c := range pkg.Classes {
+			if owners[c.Ref()] == pkg && token.IsExported(c.Name) {
+				exported++
+			}
+		}
+		node := &Class{
+			Kind:        "class",
+			Name:        pkg.Path,
+			Alias:       cleanClassName(pkg.Path),
+			Stereotypes: append([]string{}, collapsedStereotypes...),
+			Extra:       link(pkg.Path + ext),
+			Members:     []string{typeCount(exported), ""},
+		}
+		nodes[pkg] = node
+		index.Classes = append(index.Classes, node)
+	}
+	edges := []*Edge{}
+	for _, e := range d.Edges {
+		if owners[e.From] != nil && owners[e.To] != nil {
+			edges = append(edges, e)
+		}
+	}
+	index.Edges = mergeEdges(edges, func(name string) (string, bool) {
+		return nodes[owners[name]].Ref(), true
+	})
+	return index
+}
+
+// convertDiagram converts PlantUML rendered by go2uml to the format
+func convertDiagram(plantUML, format string) (string, error) {
+	switch format {
+	case "plantuml":
+		return plantUML, nil
+	case "mermaid":
+		return ConvertToMermaid(plantUML)
+	}
+	return "", fmt.Errorf("format must be plantuml or mermaid")
+}
+
+// writeSplit writes the diagrams of splitDiagram to files in dir, converted to the format. Diagrams exceeding the
+// limits are partitioned, the first part keeps the name of the diagram and the others get a number, e.g. store-2.
+func writeSplit(d *Diagram, dir, format string, limits sizeLimits) error {
+	ext := diagramExtension(format)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("could not create %s: %w", dir, err)
+	}
+	written := map[string]bool{}
+	for _, nd := range splitDiagram(d, ext) {
+		for i, part := range partitionDiagram(nd.diagram, limits) {
+			rendered, err := convertDiagram(part.Render(), format)
+			if err != nil {
+				return err
+			}
+			name := nd.name
+			if i > 0 {
+				name = fmt.Sprintf("%s-%d", name, i+1)
+			}
+			path := filepath.Join(dir, name+ext)
+			if written[path] {
+				return fmt.Errorf("could not write %s: two diagrams have the same name", path)
+			}
+			written[path] = true
+			if err := os.WriteFile(path, []byte(rendered), 0o600); err != nil {
+				return fmt.Errorf("could not write %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitDiagram(t *testing.T) {
+	d := ParseDiagram(nestedDiagram)
+	d.Notes = []*Note{{Position: "right", Target: "store.User", Text: "A user."}}
+	diagrams := splitDiagram(d, ".puml")
+
+	names := []string{}
+	byName := map[string]*Diagram{}
+	for _, nd := range diagrams {
+		names = append(names, nd.name)
+		byName[nd.name] = nd.diagram
+	}
+	if expected := []string{"app", "store", "store.sql", indexName}; !equalStrings(names, expected) {
+		t.Fatalf("splitDiagram() = %q, want %q", names, expected)
+	}
+
+	tests := []struct {
+		name  string
+		full  []string
+		stubs map[string]string
+		edges int
+		notes int
+	}{
+		{
+			name: "app",
+			full: []string{"app.Handler"},
+			stubs: map[string]string{
+				"store.Repository": "[[store.puml]]",
+				"store.User":       "[[store.puml]]",
+				"store.sql.DB":     "[[store.sql.puml]]",
+			},
+			edges: 3,
+		},
+		{
+			name: "store",
+			full: []string{"store.User", "store.Repository", "store.cache"},
+			stubs: map[string]string{
+				"app.Handler":  "[[app.puml]]",
+				"store.sql.DB": "[[store.sql.puml]]",
+			},
+			edges: 4,
+			notes: 1,
+		},
+		{
+			name: "store.sql",
+			full: []string{"store.sql.DB"},
+			stubs: map[string]string{
+				"store.Repository": "[[store.puml]]",
+				"store.User":       "[[store.puml]]",
+				"app.Handler":      "[[app.puml]]",
+			},
+			edges: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pd := byName[tt.name]
+			for _, name := range tt.full {
+				c := pd.FindClass(name)
+				if c == nil || len(c.Members) == 0 && name != "store.cache" {
+					t.Errorf("class %s not shown in full in\n%s", name, pd.Render())
+				}
+			}
+			for name, link := range tt.stubs {
+				c := pd.FindClass(name)
+				if c == nil || len(c.Members) > 0 || c.Extra != link {
+					t.Errorf("class %s is no stub linking %s in\n%s", name, link, pd.Render())
+				}
+			}
+			if len(pd.Edges) != tt.edges {
+				t.Errorf("Edges = %d, want %d", len(pd.Edges), tt.edges)
+			}
+			if len(pd.Notes) != tt.notes {
+				t.Errorf("Notes = %d, want %d", len(pd.Notes), tt.notes)
+			}
+		})
+	}
+
+	edges := []string{}
+	for _, e := range byName[indexName].Edges {
+		edges = append(edges, e.String())
+	}
+	expected := []string{
+		`"store" <|-- "store_sql"`,
+		`"app" o-- "store" : x2`,
+		`"app" o-- "store_sql" : db`,
+		`"store_sql" o-- "store" : Owner`,
+	}
+	if !equalStrings(edges, expected) {
+		t.Errorf("index Edges = %q, want %q", edges, expected)
+	}
+}
+
+func TestWriteSplit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "diagrams")
+	if err := writeSplit(ParseDiagram(nestedDiagram), dir, "mermaid", sizeLimits{}); err != nil {
+		t.Fatalf("writeSplit() error = %v", err)
+	}
+	for _, name := range []string{"app.mmd", "store.mmd", "store.sql.mmd"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("writeSplit() did not write %s: %v", name, err)
+		}
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "_index.mmd"))
+	if err != nil {
+		t.Fatalf("writeSplit() did not write the index: %v", err)
+	}
+	if !strings.Contains(string(index), `click store_sql href "store.sql.mmd"`) {
+		t.Errorf("index = %s\nwant a link to store.sql.mmd", index)
+	}
+	if err := writeSplit(ParseDiagram(nestedDiagram), dir, "dot", sizeLimits{}); err == nil {
+		t.Errorf("writeSplit() accepted an unknown format")
+	}
+}
+
+func TestWriteSplitNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram string
+		files   []string
+		err     bool
+	}{
+		{
+			name: "package named index",
+			diagram: `@startuml
+namespace index {
+    class "Entry" << (S,Aquamarine) >> {
+    }
+}
+@enduml
+`,
+			files: []string{"index.puml", "_index.puml"},
+		},
+		{
+			name: "group named like the index",
+			diagram: `@startuml
+package _index {
+    class "Entry" << (S,Aquamarine) >> {
+    }
+}
+@enduml
+`,
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := writeSplit(ParseDiagram(tt.diagram), dir, "plantuml", sizeLimits{})
+			if (err != nil) != tt.err {
+				t.Fatalf("writeSplit() error = %v, want error %t", err, tt.err)
+			}
+			for _, name := range tt.files {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("writeSplit() did not write %s: %v", name, err)
+				}
+			}
+		})
+	}
+}
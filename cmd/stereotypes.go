@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// interfaceStereotype gives the classes that have all methods of an interface a stereotype. Methods are
+// signatures in the form methodSignature returns them.
+type interfaceStereotype struct {
+	iface      string
+	stereotype string
+	methods    map[string]string
+}
+
+// wellKnownStereotypes are the standard library interfaces whose implementations get a stereotype by default
+func wellKnownStereotypes() []*interfaceStereotype {
+	return []*interfaceStereotype{
+		{iface: "error", stereotype: "error", methods: map[string]string{"Error": "() string"}},
+		{iface: "fmt.Stringer", stereotype: "Stringer", methods: map[string]string{"String": "() string"}},
+		{
+			iface:      "net/http.Handler",
+			stereotype: "Handler",
+			methods:    map[string]string{"ServeHTTP": "(net/http.ResponseWriter, *net/http.Request)"},
+		},
+		{
+			iface:      "encoding/json.Marshaler",
+			stereotype: "Marshaler",
+			methods:    map[string]string{"MarshalJSON": "() ([]byte, error)"},
+		},
+		{iface: "io.Closer", stereotype: "io.Closer", methods: map[string]string{"Close": "() error"}},
+	}
+}
+
+// wellKnownMethods finds files that declare a method of a well-known interface, so that sources without them are
+// not parsed twice
+var wellKnownMethods = regexp.MustCompile(`\)\s*(Error|String|ServeHTTP|MarshalJSON|Close)\s*\(`)
+
+// parseStereotypeMapping parses the -interface-stereotypes list of Stereotype=Interface entries, where the
+// interface is the full class name of an interface of the diagram, e.g. Repository=store.Repository
+func parseStereotypeMapping(list string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, entry := range splitList(list) {
+		stereotype, iface, ok := strings.Cut(entry, "=")
+		stereotype, iface = strings.TrimSpace(stereotype), strings.TrimSpace(iface)
+		if !ok || stereotype == "" || iface == "" {
+			return nil, fmt.Errorf("invalid interface stereotype %q, expected Stereotype=Interface", entry)
+		}
+		result[iface] = stereotype
+	}
+	return result, nil
+}
+
+// typeString returns a type expression with every named type qualified in a way that is the same in all files:
+// classes of the diagram by their full name, other types by their import path, e.g. *net/http.Request
+func typeString(expr ast.Expr, namespace string, file *ast.File, r *classResolver) string {
+	str := func(e ast.Expr) string { return typeString(e, namespace, file, r) }
+	list := func(fields *ast.FieldList) string {
+		parts := []string{}
+		if fields == nil {
+			return ""
+		}
+		for _, field := range fields.List {
+			for range max(len(field.Names), 1) {
+				parts = append(parts, str(field.Type))
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if c := r.resolve(namespace, file, e); c != nil {
+			return c.FullName()
+		}
+		if e.Name == "any" {
+			return "interface{}"
+		}
+		if types.Universe.Lookup(e.Name) != nil {
+			return e.Name
+		}
+		return namespace + "." + e.Name
+	case *ast.SelectorExpr:
+		if c := r.resolve(namespace, file, e); c != nil {
+			return c.FullName()
+		}
+		if x, ok := e.X.(*ast.Ident); ok {
+			for _, spec := range file.Imports {
+				importPath, err := strconv.Unquote(spec.Path.Value)
+				if err == nil && importName(spec, importPath) == x.Name {
+					return importPath + "." + e.Sel.Name
+				}
+			}
+		}
+	case *ast.StarExpr:
+		return "*" + str(e.X)
+	case *ast.ParenExpr:
+		return str(e.X)
+	case *ast.Ellipsis:
+		return "..." + str(e.Elt)
+	case *ast.ArrayType:
+		if e.Len == nil {
+			return "[]" + str(e.Elt)
+		}
+		return "[" + types.ExprString(e.Len) + "]" + str(e.Elt)
+	case *ast.MapType:
+		return "map[" + str(e.Key) + "]" + str(e.Value)
+	case *ast.ChanType:
+		switch e.Dir {
+		case ast.SEND:
+			return "chan<- " + str(e.Value)
+		case ast.RECV:
+			return "<-chan " + str(e.Value)
+		}
+		return "chan " + str(e.Value)
+	case *ast.FuncType:
+		return "func" + methodSignature(e, namespace, file, r)
+	case *ast.InterfaceType:
+		if len(e.Methods.List) == 0 {
+			return "interface{}"
+		}
+	case *ast.StructType:
+		return "struct{" + list(e.Fields) + "}"
+	}
+	return types.ExprString(expr)
+}
+
+// methodSignature returns the parameter and result types of a method without names, e.g. "(int) (string, error)"
+func methodSignature(fn *ast.FuncType, namespace string, file *ast.File, r *classResolver) string {
+	typeList := func(fields *ast.FieldList) []string {
+		result := []string{}
+		if fields == nil {
+			return result
+		}
+		for _, field := range fields.List {
+			for range max(len(field.Names), 1) {
+				result = append(result, typeString(field.Type, namespace, file, r))
+			}
+		}
+		return result
+	}
+	signature := "(" + strings.Join(typeList(fn.Params), ", ") + ")"
+	results := typeList(fn.Results)
+	switch len(results) {
+	case 0:
+		return signature
+	case 1:
+		return signature + " " + results[0]
+	}
+	return signature + " (" + strings.Join(results, ", ") + ")"
+}
+
+// methodSet returns the signatures of the methods declared for a type, or of the methods of an interface including
+// the ones of interfaces of the diagram it embeds
+func methodSet(c *Class, idx *sourceIndex, r *classResolver, seen map[*Class]bool) map[string]string {
+	result := map[string]string{}
+	t := idx.lookup(c)
+	if t == nil || seen[c] {
+		return result
+	}
+	seen[c] = true
+	if it, ok := t.spec.Type.(*ast.InterfaceType); ok {
+		for _, field := range it.Methods.List {
+			fn, ok := field.Type.(*ast.FuncType)
+			if !ok {
+				if embedded := r.resolve(c.SourceNamespace(), t.file, field.Type); embedded != nil {
+					for name, signature := range methodSet(embedded, idx, r, seen) {
+						result[name] = signature
+					}
+				}
+				continue
+			}
+			for _, name := range field.Names {
+				result[name.Name] = methodSignature(fn, c.SourceNamespace(), t.file, r)
+			}
+		}
+	}
+	for _, m := range t.methods {
+		result[m.decl.Name.Name] = methodSignature(m.decl.Type, c.SourceNamespace(), m.file, r)
+	}
+	return result
+}
+
+// interfaceStereotypes returns the well-known interfaces, unless hidden, followed by the interfaces of the diagram
+// the mapping gives a stereotype. Well-known interfaces in the mapping get the stereotype of the mapping and are
+// kept even if hidden.
+func interfaceStereotypes(d *Diagram, idx *sourceIndex, mapping map[string]string, hide bool) []*interfaceStereotype {
+	result := []*interfaceStereotype{}
+	mapped := map[string]bool{}
+	for _, s := range wellKnownStereotypes() {
+		if stereotype, ok := mapping[s.iface]; ok {
+			s.stereotype = stereotype
+			mapped[s.iface] = true
+		} else if hide {
+			continue
+		}
+		result = append(result, s)
+	}
+	r := newClassResolver(d)
+	names := []string{}
+	for name := range mapping {
+		if !mapped[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := d.FindClass(name)
+		if c == nil || c.Kind != "interface" || idx.lookup(c) == nil {
+			slog.Warn("interface for stereotype not found in the diagram", "interface", name)
+			continue
+		}
+		result = append(result, &interfaceStereotype{
+			iface:      name,
+			stereotype: mapping[name],
+			methods:    methodSet(c, idx, r, map[*Class]bool{}),
+		})
+	}
+	return result
+}
+
+// addInterfaceStereotypes adds the stereotype of every interface to the classes that declare all of its methods
+// with the same signatures
+func addInterfaceStereotypes(d *Diagram, idx *sourceIndex, stereotypes []*interfaceStereotype) {
+	r := newClassResolver(d)
+	for _, c := range d.AllClasses() {
+		if idx.lookup(c) == nil {
+			continue
+		}
+		methods := methodSet(c, idx, r, map[*Class]bool{})
+		for _, s := range stereotypes {
+			if s.iface == c.FullName() || len(s.methods) == 0 || containsString(c.Stereotypes, s.stereotype) {
+				continue
+			}
+			implements := true
+			for name, signature := range s.methods {
+				implements = implements && methods[name] == signature
+			}
+			if implements {
+				c.Stereotypes = append(c.Stereotypes, s.stereotype)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestParseStereotypeMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{name: "empty", list: "", expected: map[string]string{}},
+		{
+			name:     "entries",
+			list:     "Repository=store.Repository, Closer = io.Closer",
+			expected: map[string]string{"store.Repository": "Repository", "io.Closer": "Closer"},
+		},
+		{name: "missing interface", list: "Repository=", wantErr: true},
+		{name: "missing stereotype", list: "store.Repository", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseStereotypeMapping(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStereotypeMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(result) != len(tt.expected) {
+				t.Errorf("parseStereotypeMapping() = %v, want %v", result, tt.expected)
+			}
+			for iface, stereotype := range tt.expected {
+				if result[iface] != stereotype {
+					t.Errorf("parseStereotypeMapping()[%s] = %q, want %q", iface, result[iface], stereotype)
+				}
+			}
+		})
+	}
+}
+
+// stereotypedSource declares implementations of well-known interfaces and of an interface of the diagram
+const stereotypedSource = `package store
+
+import (
+	"io"
+	"net/http"
+	web "net/http"
+)
+
+type Repository interface {
+	io.Closer
+	Find(id int) (*Record, error)
+}
+
+type Record struct{}
+
+func (r Record) String() string                 { return "" }
+func (r Record) MarshalJSON() ([]byte, error)   { return nil, nil }
+
+type NotFound struct{}
+
+func (e *NotFound) Error() string { return "" }
+
+type Server struct{}
+
+func (s *Server) ServeHTTP(w web.ResponseWriter, r *http.Request) {}
+
+type SQL struct{}
+
+func (s *SQL) Close() error                             { return nil }
+func (s *SQL) Find(id int) (*Record, error)             { return nil, nil }
+
+type Almost struct{}
+
+func (a *Almost) String(verbose bool) string            { return "" }
+func (a *Almost) Find(id string) (*Record, error)       { return nil, nil }
+`
+
+func TestAddInterfaceStereotypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  map[string]string
+		hide     bool
+		expected map[string][]string
+	}{
+		{
+			name: "well-known interfaces",
+			expected: map[string][]string{
+				"store.Record":   {"Stringer", "Marshaler"},
+				"store.NotFound": {"error"},
+				"store.Server":   {"Handler"},
+				"store.SQL":      {"io.Closer"},
+				"store.Almost":   {},
+			},
+		},
+		{
+			name:    "mapping",
+			mapping: map[string]string{"store.Repository": "Repository", "io.Closer": "Closer"},
+			hide:    true,
+			expected: map[string][]string{
+				"store.Record":   {},
+				"store.NotFound": {},
+				"store.SQL":      {"Closer", "Repository"},
+				"store.Almost":   {},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeFiles(t, map[string]string{"store/store.go": stereotypedSource})
+			dirs := []string{filepath.Join(root, "store")}
+			sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+			if err != nil {
+				t.Fatalf("collectSources() error = %v", err)
+			}
+			if !sources.wellKnown {
+				t.Fatalf("collectSources() found no methods of well-known interfaces")
+			}
+			result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", root, err)
+			}
+			d := ParseDiagram(result.Render())
+			idx := loadSourceIndex(sources, dirs)
+			addInterfaceStereotypes(d, idx, interfaceStereotypes(d, idx, tt.mapping, tt.hide))
+
+			for name, expected := range tt.expected {
+				stereotypes := plainStereotypes(d.FindClass(name))
+				if !equalStrings(stereotypes, expected) {
+					t.Errorf("stereotypes of %s = %q, want %q", name, stereotypes, expected)
+				}
+			}
+		})
+	}
+}
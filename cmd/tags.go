@@ -0,0 +1,135 @@
+package main
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// tagFilter is the value of -show-tags. It can be given as a switch to show all struct tags, or with a comma
+// separated list of the tag keys to show, e.g. -show-tags=json,db.
+type tagFilter struct {
+	enabled bool
+	keys    []string
+}
+
+// String returns the keys of the filter
+func (f *tagFilter) String() string {
+	if f == nil || !f.enabled {
+		return ""
+	}
+	return strings.Join(f.keys, ",")
+}
+
+// Set enables the filter, for all keys if the value is true or for the listed ones
+func (f *tagFilter) Set(value string) error {
+	if enabled, err := strconv.ParseBool(value); err == nil {
+		f.enabled = enabled
+		f.keys = nil
+		return nil
+	}
+	f.enabled = true
+	f.keys = splitList(value)
+	return nil
+}
+
+// IsBoolFlag allows -show-tags to be used without a value
+func (f *tagFilter) IsBoolFlag() bool {
+	return true
+}
+
+// structTag is one key and value of a struct tag
+type structTag struct {
+	key   string
+	value string
+}
+
+// parseStructTag splits a struct tag into its key and value pairs, in their order. It follows the conventional
+// format reflect.StructTag.Lookup expects and stops at the first malformed pair.
+func parseStructTag(tag string) []structTag {
+	result := []structTag{}
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		result = append(result, structTag{key: key, value: value})
+		tag = tag[i+1:]
+	}
+	return result
+}
+
+// render returns the tags the filter shows, formatted like they are written in Go
+func (f *tagFilter) render(tag string) string {
+	parts := []string{}
+	for _, t := range parseStructTag(tag) {
+		if len(f.keys) == 0 || containsString(f.keys, t.key) {
+			parts = append(parts, t.key+":"+strconv.Quote(t.value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// memberName returns the name of a field or method line of a class body, e.g. "Name" for "+ Name string" and
+// "Save" for "+ Save(u User) error". Members struck through as deprecated are named the same.
+func memberName(member string) string {
+	member = strings.TrimPrefix(strings.TrimLeft(member, "+-#~ "), "<s>")
+	if i := strings.IndexAny(member, " ("); i >= 0 {
+		return member[:i]
+	}
+	return member
+}
+
+// addTags appends the struct tags of every field as a stereotype to its member line
+func addTags(d *Diagram, idx *sourceIndex, filter *tagFilter) {
+	for _, c := range d.AllClasses() {
+		t := idx.lookup(c)
+		if t == nil {
+			continue
+		}
+		st, ok := t.spec.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		tags := map[string]string{}
+		for _, field := range st.Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue
+			}
+			if rendered := filter.render(tag); rendered != "" {
+				for _, name := range field.Names {
+					tags[name.Name] = rendered
+				}
+			}
+		}
+		for i, m := range c.Members {
+			if rendered, ok := tags[memberName(m)]; ok {
+				c.Members[i] = m + " <<" + rendered + ">>"
+			}
+		}
+	}
+}
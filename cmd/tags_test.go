@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestParseStructTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected []structTag
+	}{
+		{
+			name:     "single key",
+			tag:      `json:"id"`,
+			expected: []structTag{{key: "json", value: "id"}},
+		},
+		{
+			name:     "several keys in order",
+			tag:      `json:"name,omitempty" db:"user_name"  xml:"n"`,
+			expected: []structTag{{"json", "name,omitempty"}, {"db", "user_name"}, {"xml", "n"}},
+		},
+		{
+			name:     "escaped quote",
+			tag:      `doc:"a \"quoted\" word"`,
+			expected: []structTag{{key: "doc", value: `a "quoted" word`}},
+		},
+		{
+			name:     "malformed pair stops parsing",
+			tag:      `json:"id" broken db:"x"`,
+			expected: []structTag{{key: "json", value: "id"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseStructTag(tt.tag)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parseStructTag() = %v, want %v", result, tt.expected)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("parseStructTag()[%d] = %v, want %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTagFilterSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		enabled bool
+		keys    []string
+	}{
+		{name: "switch", value: "true", enabled: true, keys: nil},
+		{name: "disabled", value: "false", enabled: false, keys: nil},
+		{name: "keys", value: "json, db", enabled: true, keys: []string{"json", "db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &tagFilter{}
+			if err := f.Set(tt.value); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if f.enabled != tt.enabled || !equalStrings(f.keys, tt.keys) {
+				t.Errorf("Set() = %+v, want enabled %v and keys %v", f, tt.enabled, tt.keys)
+			}
+		})
+	}
+}
+
+func TestAddTags(t *testing.T) {
+	root := writeFiles(t, map[string]string{"store/user.go": "package store\n\n" +
+		"type User struct {\n" +
+		"\tID int `json:\"id\" db:\"user_id\"`\n" +
+		"\tName string `json:\"name,omitempty\" xml:\"name\"`\n" +
+		"\tEmail string\n" +
+		"}\n",
+	})
+	dirs := []string{filepath.Join(root, "store")}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{
+			name: "all keys",
+			expected: []string{
+				`+ ID int <<json:"id" db:"user_id">>`,
+				`+ Name string <<json:"name,omitempty" xml:"name">>`,
+				`+ Email string`,
+			},
+		},
+		{
+			name: "filtered keys",
+			keys: []string{"db"},
+			expected: []string{
+				`+ ID int <<db:"user_id">>`,
+				`+ Name string`,
+				`+ Email string`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := collectSources(dirs, false, nil, &sourceFilter{})
+			if err != nil {
+				t.Fatalf("collectSources() error = %v", err)
+			}
+			result, err := goplantuml.NewClassDiagramWithMaxDepth(dirs, []string{}, false, 0)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", root, err)
+			}
+			d := ParseDiagram(result.Render())
+			addTags(d, loadSourceIndex(sources, dirs), &tagFilter{enabled: true, keys: tt.keys})
+
+			members := []string{}
+			for _, m := range d.FindClass("store.User").Members {
+				if m != "" {
+					members = append(members, m)
+				}
+			}
+			if !equalStrings(members, tt.expected) {
+				t.Errorf("Members = %q, want %q", members, tt.expected)
+			}
+		})
+	}
+}
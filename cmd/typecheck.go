@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	implementsArrow  = "<|--"
+	compositionArrow = "*--"
+	implementsLabel  = "implements"
+	extendsLabel     = "extends"
+)
+
+// typedPackages are the type-checked Go packages of a diagram, together with the namespace goplantuml gave them
+// and the external interfaces resolved in the same load, so that their method signatures refer to the same types
+type typedPackages struct {
+	packages   []*packages.Package
+	namespaces map[*types.Package]string
+	external   map[*types.Package][]*types.TypeName
+}
+
+// loadTypedPackages loads and type checks the Go packages in dirs. Each directory is loaded from its own module,
+// with ./... when the directories are walked recursively. Packages in ignored directories are left out.
+// External interfaces are given as import path and name, e.g. net/http.Handler, or error. A build context selects
+// the GOOS, GOARCH and build tags the packages are loaded for, nil loads them for the go tool's defaults.
+func loadTypedPackages(
+	dirs []string,
+	recursive bool,
+	ignored []string,
+	external []string,
+	ctx *build.Context,
+) (*typedPackages, error) {
+	result := &typedPackages{
+		namespaces: map[*types.Package]string{},
+		external:   map[*types.Package][]*types.TypeName{},
+	}
+	seen := map[string]struct{}{}
+	patterns := []string{"."}
+	if recursive {
+		patterns = []string{"./..."}
+	}
+	for _, name := range external {
+		if pkgPath, _, ok := splitInterfaceName(name); ok && !containsString(patterns, pkgPath) {
+			patterns = append(patterns, pkgPath)
+		}
+	}
+	for _, dir := range dirs {
+		cfg := &packages.Config{
+			Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+				packages.NeedTypes | packages.NeedSyntax,
+			Dir:       dir,
+			ParseFile: parseDeclarations(dirs),
+		}
+		if ctx != nil {
+			cfg.Env = append(os.Environ(), "GOOS="+ctx.GOOS, "GOARCH="+ctx.GOARCH)
+			cfg.BuildFlags = []string{"-tags=" + strings.Join(ctx.BuildTags, ",")}
+		}
+		loaded, err := packages.Load(cfg, patterns...)
+		if err != nil {
+			return nil, fmt.Errorf("could not load packages in %s: %w", dir, err)
+		}
+		interfaces := resolveInterfaces(loaded, external)
+		for _, p := range loaded {
+			if len(p.GoFiles) == 0 || p.Types == nil {
+				continue
+			}
+			pkgDir := filepath.Dir(p.GoFiles[0])
+			if !isInsideAny(pkgDir, []string{dir}) || isInsideAny(pkgDir, ignored) {
+				continue
+			}
+			if _, ok := seen[p.PkgPath]; ok {
+				continue
+			}
+			seen[p.PkgPath] = struct{}{}
+			for _, e := range p.Errors {
+				slog.Warn("type checking failed, relationships may be incomplete", "package", p.PkgPath, "error", e)
+			}
+			result.packages = append(result.packages, p)
+			result.namespaces[p.Types] = namespacePath(pkgDir, dirs)
+			result.external[p.Types] = interfaces
+		}
+	}
+	return result, nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDeclarations returns a parser for packages.Config that drops the function bodies of files outside of the
+// diagram directories. Dependencies are type checked from source, which does not depend on the export data
+// format of the installed Go version, and their declarations are all the relationships need.
+func parseDeclarations(dirs []string) func(*token.FileSet, string, []byte) (*ast.File, error) {
+	return func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+		file, err := parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+		if file == nil || isInsideAny(filepath.Dir(filename), dirs) {
+			return file, err
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				fn.Body = nil
+			}
+		}
+		return file, err
+	}
+}
+
+// isInsideAny reports whether dir is one of the given directories or inside one of them
+func isInsideAny(dir string, dirs []string) bool {
+	for _, d := range dirs {
+		if isWithin(dir, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// packageNamespace mirrors goplantuml's calculatePackagePath. It returns the dotted namespace goplantuml gives
+// to the package in dir when the given root directories are parsed, so that types can be matched to classes.
+func packageNamespace(dir string, roots []string) string {
+	absPath, _ := filepath.Abs(dir)
+	shortestRoot := ""
+	for _, root := range roots {
+		rootAbs, _ := filepath.Abs(root)
+		if strings.HasPrefix(absPath, rootAbs) && (shortestRoot == "" || len(rootAbs) < len(shortestRoot)) {
+			shortestRoot = rootAbs
+		}
+	}
+	if shortestRoot == "" {
+		return filepath.Base(absPath)
+	}
+	relPath, err := filepath.Rel(shortestRoot, absPath)
+	if err != nil {
+		return filepath.Base(absPath)
+	}
+	packagePath := strings.ReplaceAll(relPath, string(filepath.Separator), ".")
+	switch {
+	case packagePath == ".":
+		return filepath.Base(shortestRoot)
+	case !strings.Contains(relPath, string(filepath.Separator)):
+		return packagePath
+	case strings.HasPrefix(relPath, "testingsupport") || strings.HasPrefix(relPath, "cmd"):
+		return packagePath
+	}
+	rootName := filepath.Base(shortestRoot)
+	if rootName != "." {
+		return rootName + "." + packagePath
+	}
+	return packagePath
+}
+
+// namespacePath returns the dotted path of the namespace block goplantuml draws the package in dir in. goplantuml
+// nests a namespace in the one of its parent package only if the parent's path, read as a directory relative to a
+// root, exists. Otherwise the namespace is drawn at the top level with the last element of its path as name.
+func namespacePath(dir string, roots []string) string {
+	return displayPath(packageNamespace(dir, roots), roots)
+}
+
+// displayPath returns the namespace block path of a goplantuml package path
+func displayPath(packagePath string, roots []string) string {
+	lastDot := strings.LastIndex(packagePath, ".")
+	if lastDot < 0 {
+		return packagePath
+	}
+	parent, name := packagePath[:lastDot], packagePath[lastDot+1:]
+	for _, root := range roots {
+		info, err := os.Stat(filepath.Join(root, strings.ReplaceAll(parent, ".", string(filepath.Separator))))
+		if err == nil && info.IsDir() {
+			return displayPath(parent, roots) + "." + name
+		}
+	}
+	return name
+}
+
+// repairRelationships points the relationships goplantuml draws for the packages in dirs to the namespaces their
+// classes are drawn in. goplantuml qualifies them with the package path, which differs from the namespace for
+// packages drawn at the top level although they are nested in directories.
+func repairRelationships(d *Diagram, dirs []string, roots []string) {
+	renamed := map[string]string{}
+	for _, dir := range dirs {
+		if packagePath := packageNamespace(dir, roots); packagePath != namespacePath(dir, roots) {
+			renamed[packagePath] = namespacePath(dir, roots)
+		}
+	}
+	if len(renamed) == 0 {
+		return
+	}
+	rename := func(name string) string {
+		lastDot := strings.LastIndex(name, ".")
+		if lastDot < 0 {
+			return name
+		}
+		if ns, ok := renamed[name[:lastDot]]; ok {
+			return ns + name[lastDot:]
+		}
+		return name
+	}
+	for _, e := range d.Edges {
+		e.From = rename(e.From)
+		e.To = rename(e.To)
+	}
+}
+
+// className returns the name relationships use for a named type: the namespace and type name for types of the
+// diagram, the last element of the import path and the type name for types of other packages
+func (tp *typedPackages) className(obj *types.TypeName) string {
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	if ns, ok := tp.namespaces[obj.Pkg()]; ok {
+		return ns + "." + obj.Name()
+	}
+	return path.Base(obj.Pkg().Path()) + "." + obj.Name()
+}
+
+// namedTypes returns the package level named types of all packages, sorted by class name
+func (tp *typedPackages) namedTypes() []*types.TypeName {
+	result := []*types.TypeName{}
+	for _, p := range tp.packages {
+		scope := p.Types.Scope()
+		for _, name := range scope.Names() {
+			if obj, ok := scope.Lookup(name).(*types.TypeName); ok && !obj.IsAlias() {
+				result = append(result, obj)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return tp.className(result[i]) < tp.className(result[j])
+	})
+	return result
+}
+
+// implementsInterface reports whether the type or a pointer to it implements the interface
+func implementsInterface(t types.Type, iface *types.Interface) bool {
+	if types.IsInterface(t) || iface.NumMethods() == 0 {
+		return false
+	}
+	return types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface)
+}
+
+// isGeneric reports whether the named type declares type parameters
+func isGeneric(obj *types.TypeName) bool {
+	named, ok := obj.Type().(*types.Named)
+	return ok && named.TypeParams().Len() > 0
+}
+
+// embeddedTypes returns the named types embedded in a struct or an interface
+func embeddedTypes(obj *types.TypeName) []*types.TypeName {
+	result := []*types.TypeName{}
+	add := func(t types.Type) {
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := t.(*types.Named); ok {
+			result = append(result, named.Origin().Obj())
+		}
+	}
+	switch u := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		for i := range u.NumFields() {
+			if u.Field(i).Embedded() {
+				add(u.Field(i).Type())
+			}
+		}
+	case *types.Interface:
+		for i := range u.NumEmbeddeds() {
+			add(u.EmbeddedType(i))
+		}
+	}
+	return result
+}
+
+// typedRelations computes the implements and embeds relationships between the types of the diagram from their
+// method sets. Implementations are only returned between classes of the diagram, embedded types may be external.
+func (tp *typedPackages) typedRelations(d *Diagram, labels bool) ([]*Edge, []*Edge) {
+	inDiagram := map[string]struct{}{}
+	for _, c := range d.AllClasses() {
+		inDiagram[c.FullName()] = struct{}{}
+	}
+	label := func(l string) string {
+		if labels {
+			return l
+		}
+		return ""
+	}
+
+	named := tp.namedTypes()
+	interfaces := []*types.TypeName{}
+	for _, obj := range named {
+		if types.IsInterface(obj.Type()) && !isGeneric(obj) {
+			interfaces = append(interfaces, obj)
+		}
+	}
+
+	implements := []*Edge{}
+	embeds := []*Edge{}
+	for _, obj := range named {
+		name := tp.className(obj)
+		if _, ok := inDiagram[name]; !ok {
+			continue
+		}
+		for _, embedded := range embeddedTypes(obj) {
+			embeds = append(embeds, &Edge{
+				From:    tp.className(embedded),
+				Arrow:   compositionArrow,
+				ToLabel: label(extendsLabel),
+				To:      name,
+			})
+		}
+		if isGeneric(obj) {
+			continue
+		}
+		for _, iface := range interfaces {
+			ifaceName := tp.className(iface)
+			if _, ok := inDiagram[ifaceName]; !ok {
+				continue
+			}
+			if implementsInterface(obj.Type(), iface.Type().Underlying().(*types.Interface)) {
+				implements = append(implements, &Edge{
+					From:    ifaceName,
+					Arrow:   implementsArrow,
+					ToLabel: label(implementsLabel),
+					To:      name,
+				})
+			}
+		}
+	}
+	return implements, embeds
+}
+
+// applyTypedRelations replaces the implementations and compositions goplantuml inferred from method names with
+// the ones computed from the type-checked packages. The rendering options decide which of them are drawn.
+func applyTypedRelations(d *Diagram, tp *typedPackages, ro map[goplantuml.RenderingOption]any) {
+	implements, embeds := tp.typedRelations(d, renderingOption(ro, goplantuml.RenderConnectionLabels, false))
+	d.RemoveEdges(func(e *Edge) bool {
+		return e.Arrow == implementsArrow || e.Arrow == compositionArrow
+	})
+	if renderingOption(ro, goplantuml.RenderCompositions, true) {
+		for _, e := range embeds {
+			d.AddEdge(e)
+		}
+	}
+	if renderingOption(ro, goplantuml.RenderImplementations, true) {
+		for _, e := range implements {
+			d.AddEdge(e)
+		}
+	}
+}
+
+// renderingOption returns a boolean rendering option, or the goplantuml default if it was not set
+func renderingOption(ro map[goplantuml.RenderingOption]any, option goplantuml.RenderingOption, def bool) bool {
+	if val, ok := ro[option].(bool); ok {
+		return val
+	}
+	return def
+}
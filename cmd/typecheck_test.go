@@ -0,0 +1,238 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	goplantuml "github.com/jfeliu007/goplantuml/parser"
+)
+
+// shapesSource declares types whose relationships goplantuml gets wrong: Square only implements Shape through a
+// method promoted from Base and a pointer receiver, Circle has the method of Shape but not the embedded Namer.
+const shapesSource = `package shapes
+
+type Namer interface {
+	Name() string
+}
+
+type Shape interface {
+	Namer
+	Area() float64
+}
+
+type Base struct{}
+
+func (Base) Name() string { return "" }
+
+type Square struct {
+	Base
+	side float64
+}
+
+func (s *Square) Area() float64 { return s.side * s.side }
+
+type Circle struct {
+	r float64
+}
+
+func (c Circle) Area() float64 { return c.r * c.r * 3 }
+`
+
+// typedDiagram renders the goplantuml diagram of dir and applies the type-checked relationships
+func typedDiagram(t *testing.T, dir string, ro map[goplantuml.RenderingOption]any) *Diagram {
+	t.Helper()
+	result, err := goplantuml.NewClassDiagramWithMaxDepth([]string{dir}, []string{}, false, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", dir, err)
+	}
+	_ = result.SetRenderingOptions(ro)
+	d := ParseDiagram(result.Render())
+	typed, err := loadTypedPackages([]string{dir}, false, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("loadTypedPackages() error = %v", err)
+	}
+	applyTypedRelations(d, typed, ro)
+	return d
+}
+
+func TestApplyTypedRelations(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":           "module example.com/shapes\n\ngo 1.25\n",
+		"shapes/shapes.go": shapesSource,
+	})
+	d := typedDiagram(t, filepath.Join(root, "shapes"), map[goplantuml.RenderingOption]any{})
+
+	expected := []Edge{
+		{From: "shapes.Namer", Arrow: "*--", To: "shapes.Shape"},
+		{From: "shapes.Base", Arrow: "*--", To: "shapes.Square"},
+		{From: "shapes.Namer", Arrow: "<|--", To: "shapes.Base"},
+		{From: "shapes.Namer", Arrow: "<|--", To: "shapes.Square"},
+		{From: "shapes.Shape", Arrow: "<|--", To: "shapes.Square"},
+	}
+	if len(d.Edges) != len(expected) {
+		t.Fatalf("Edges = %v, want %v", d.Edges, expected)
+	}
+	for i := range expected {
+		if *d.Edges[i] != expected[i] {
+			t.Errorf("Edges[%d] = %+v, want %+v", i, *d.Edges[i], expected[i])
+		}
+	}
+}
+
+func TestApplyTypedRelationsRenderingOptions(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":           "module example.com/shapes\n\ngo 1.25\n",
+		"shapes/shapes.go": shapesSource,
+	})
+	d := typedDiagram(t, filepath.Join(root, "shapes"), map[goplantuml.RenderingOption]any{
+		goplantuml.RenderCompositions:     false,
+		goplantuml.RenderConnectionLabels: true,
+	})
+
+	for _, e := range d.Edges {
+		if e.Arrow == "*--" {
+			t.Errorf("composition %v rendered although compositions are hidden", e)
+		}
+		if e.Arrow == "<|--" && e.ToLabel != "implements" {
+			t.Errorf("implementation %v rendered without its label", e)
+		}
+	}
+	if len(d.Edges) != 3 {
+		t.Errorf("Edges = %v, want 3 implementations", d.Edges)
+	}
+}
+
+func TestApplyTypedRelationsNestedPackage(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/nested\n\ngo 1.25\n",
+		"internal/db/db.go": `package db
+
+type Store interface {
+	Get(key string) string
+}
+
+type SQLStore struct{}
+
+func (s *SQLStore) Get(key string) string { return key }
+`,
+	})
+	result, err := goplantuml.NewClassDiagramWithMaxDepth([]string{root}, []string{}, true, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", root, err)
+	}
+	d := ParseDiagram(result.Render())
+	typed, err := loadTypedPackages([]string{root}, true, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("loadTypedPackages() error = %v", err)
+	}
+	repairRelationships(d, []string{filepath.Join(root, "internal", "db")}, []string{root})
+	applyTypedRelations(d, typed, map[goplantuml.RenderingOption]any{})
+
+	expected := Edge{From: "db.Store", Arrow: "<|--", To: "db.SQLStore"}
+	if len(d.Edges) != 1 || *d.Edges[0] != expected {
+		t.Errorf("Edges = %v, want %+v", d.Edges, expected)
+	}
+}
+
+func TestPackageNamespace(t *testing.T) {
+	root := filepath.FromSlash("/src/project")
+	tests := []struct {
+		name     string
+		dir      string
+		roots    []string
+		expected string
+	}{
+		{
+			name:     "root directory",
+			dir:      root,
+			roots:    []string{root},
+			expected: "project",
+		},
+		{
+			name:     "direct subdirectory",
+			dir:      filepath.Join(root, "api"),
+			roots:    []string{root},
+			expected: "api",
+		},
+		{
+			name:     "nested subdirectory",
+			dir:      filepath.Join(root, "internal", "db"),
+			roots:    []string{root},
+			expected: "project.internal.db",
+		},
+		{
+			name:     "nested cmd directory",
+			dir:      filepath.Join(root, "cmd", "tool"),
+			roots:    []string{root},
+			expected: "cmd.tool",
+		},
+		{
+			name:     "shortest root wins",
+			dir:      filepath.Join(root, "internal", "db"),
+			roots:    []string{filepath.Join(root, "internal"), root},
+			expected: "project.internal.db",
+		},
+		{
+			name:     "outside of all roots",
+			dir:      filepath.FromSlash("/elsewhere/lib"),
+			roots:    []string{root},
+			expected: "lib",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := packageNamespace(tt.dir, tt.roots); result != tt.expected {
+				t.Errorf("packageNamespace() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNamespacePath(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"api/api.go":               "package api\n",
+		"cmd/tool/main.go":         "package main\n",
+		"internal/db/db.go":        "package db\n",
+		"internal/db/sql/sql.go":   "package sql\n",
+		"internal/api/v1/api.go":   "package v1\n",
+		"internal/api/v1/types.go": "package v1\n",
+	})
+	roots := []string{root}
+
+	tests := []struct {
+		name     string
+		dir      string
+		expected string
+	}{
+		{name: "direct subdirectory", dir: "api", expected: "api"},
+		{name: "nested cmd directory", dir: filepath.Join("cmd", "tool"), expected: "cmd.tool"},
+		{name: "nested directory", dir: filepath.Join("internal", "db"), expected: "db"},
+		{name: "deeply nested directory", dir: filepath.Join("internal", "db", "sql"), expected: "sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := namespacePath(filepath.Join(root, tt.dir), roots); result != tt.expected {
+				t.Errorf("namespacePath() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairRelationships(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"internal/db/db.go": "package db\n\ntype Conn struct{}\n",
+		"api/api.go":        "package api\n",
+	})
+	prefix := filepath.Base(root) + ".internal.db"
+	d := &Diagram{Edges: []*Edge{
+		{From: prefix + ".Conn", Arrow: "*--", To: "api.Handler"},
+		{From: "api.Handler", Arrow: "o--", To: prefix + ".Conn"},
+	}}
+	repairRelationships(d, []string{filepath.Join(root, "internal", "db"), filepath.Join(root, "api")}, []string{root})
+
+	if d.Edges[0].From != "db.Conn" || d.Edges[1].To != "db.Conn" || d.Edges[0].To != "api.Handler" {
+		t.Errorf("Edges = %v, want them to refer to db.Conn", d.Edges)
+	}
+}
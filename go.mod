@@ -2,9 +2,14 @@ module github.com/kstieger/go2uml
 
 go 1.25.1
 
-require github.com/jfeliu007/goplantuml v1.6.3
+require (
+	github.com/jfeliu007/goplantuml v1.6.3
+	golang.org/x/tools v0.41.0
+)
 
 require (
 	github.com/spf13/afero v1.8.2 // indirect
+	golang.org/x/mod v0.32.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/text v0.3.7 // indirect
 )